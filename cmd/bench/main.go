@@ -0,0 +1,153 @@
+// Command bench seeds synthetic merchants and measures the latency of nearby
+// merchant searches against them. It connects with the same DB_* env as the
+// service, e.g.
+//
+//	go run ./cmd/bench -merchants 1000000 -queries 200
+//	go run ./cmd/bench -queries 200 -geoindex
+//	go run ./cmd/bench -cleanup
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/citadel-corp/belimang/internal/common/db"
+	"github.com/citadel-corp/belimang/internal/common/geoindex"
+	"github.com/citadel-corp/belimang/internal/merchants"
+)
+
+// uidPrefix marks the seeded merchants so they can be told apart and removed.
+const uidPrefix = "bench"
+
+func main() {
+	var (
+		merchantCount = flag.Int("merchants", 0, "synthetic merchants to seed before measuring")
+		queries       = flag.Int("queries", 100, "nearby searches to measure per mode")
+		limit         = flag.Int("limit", 5, "merchants per page")
+		maxDistance   = flag.Float64("max-distance", 3000, "maxDistance of the searches in meters, 0 for none")
+		lat           = flag.Float64("lat", -6.2, "latitude the merchants are seeded around")
+		lng           = flag.Float64("lng", 106.8, "longitude the merchants are seeded around")
+		spread        = flag.Float64("spread", 0.5, "degrees the merchants spread around lat and lng")
+		seed          = flag.Int64("seed", 1, "random seed of the search locations")
+		cleanup       = flag.Bool("cleanup", false, "remove the seeded merchants and exit")
+		geoIndex      = flag.Bool("geoindex", false, "search the in-memory geo index instead of the database")
+		precision     = flag.Int("precision", geoindex.DefaultPrecision, "geohash length of the geo index cells")
+	)
+	flag.Parse()
+
+	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?%s",
+		os.Getenv("DB_USERNAME"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), os.Getenv("DB_NAME"), os.Getenv("DB_PARAMS"))
+	database, err := db.Connect(connStr)
+	if err != nil {
+		fail("cannot connect to database: %v", err)
+	}
+	ctx := context.Background()
+
+	if *cleanup {
+		res, err := database.DB().ExecContext(ctx, "DELETE FROM merchants WHERE uid LIKE $1", uidPrefix+"%")
+		if err != nil {
+			fail("cleanup failed: %v", err)
+		}
+		n, _ := res.RowsAffected()
+		fmt.Printf("removed %d seeded merchants\n", n)
+		return
+	}
+
+	if *merchantCount > 0 {
+		start := time.Now()
+		if err = seedMerchants(ctx, database, *merchantCount, *lat, *lng, *spread); err != nil {
+			fail("seeding failed: %v", err)
+		}
+		fmt.Printf("seeded %d merchants in %s\n", *merchantCount, time.Since(start).Round(time.Millisecond))
+	}
+
+	repository := merchants.NewRepository(database)
+	if *geoIndex {
+		start := time.Now()
+		indexedRepository, err := merchants.NewIndexedRepository(ctx, repository, *precision)
+		if err != nil {
+			fail("loading the geo index failed: %v", err)
+		}
+		fmt.Printf("loaded %d merchants into the geo index in %s\n", indexedRepository.Len(), time.Since(start).Round(time.Millisecond))
+		repository = indexedRepository
+	}
+	rnd := rand.New(rand.NewSource(*seed))
+	emptyCursor := ""
+	for _, mode := range []struct {
+		name      string
+		cursor    *string
+		withTotal bool
+	}{
+		{name: "offset", cursor: nil},
+		{name: "offset+total", cursor: nil, withTotal: true},
+		{name: "cursor", cursor: &emptyCursor},
+	} {
+		latencies := make([]time.Duration, 0, *queries)
+		for i := 0; i < *queries; i++ {
+			filter := merchants.ListMerchantsByDistancePayload{
+				Lat:         strconv.FormatFloat(*lat+(rnd.Float64()*2-1)*(*spread), 'f', 6, 64),
+				Lng:         strconv.FormatFloat(*lng+(rnd.Float64()*2-1)*(*spread), 'f', 6, 64),
+				MaxDistance: *maxDistance,
+				Limit:       *limit,
+				Cursor:      mode.cursor,
+				WithTotal:   mode.withTotal,
+			}
+			start := time.Now()
+			if _, _, err = repository.ListByDistance(ctx, filter); err != nil {
+				fail("nearby search failed: %v", err)
+			}
+			latencies = append(latencies, time.Since(start))
+		}
+		report(mode.name, latencies)
+	}
+}
+
+// seedMerchants inserts count merchants spread uniformly around lat and lng,
+// generating the rows in the database to keep large seeds fast.
+func seedMerchants(ctx context.Context, database *db.DB, count int, lat, lng, spread float64) error {
+	var existing int
+	err := database.DB().QueryRowContext(ctx, "SELECT COUNT(*) FROM merchants WHERE uid LIKE $1", uidPrefix+"%").Scan(&existing)
+	if err != nil {
+		return err
+	}
+	q := `
+		INSERT INTO merchants (uid, name, merchant_category, image_url, location_lat, location_lng)
+		SELECT
+			$1 || lpad(i::text, 11, '0'),
+			'Bench Merchant ' || i,
+			(enum_range(NULL::merchant_category))[1 + i % 6],
+			'https://example.com/merchant.jpg',
+			$2 + (random() * 2 - 1) * $4,
+			$3 + (random() * 2 - 1) * $4
+		FROM generate_series($5::int, $6::int) AS i
+	`
+	_, err = database.DB().ExecContext(ctx, q, uidPrefix, lat, lng, spread, existing+1, existing+count)
+	if err != nil {
+		return err
+	}
+	_, err = database.DB().ExecContext(ctx, "ANALYZE merchants")
+	return err
+}
+
+func report(mode string, latencies []time.Duration) {
+	if len(latencies) == 0 {
+		return
+	}
+	slices.Sort(latencies)
+	percentile := func(p float64) time.Duration {
+		return latencies[int(p*float64(len(latencies)-1))].Round(10 * time.Microsecond)
+	}
+	fmt.Printf("%-6s n=%d p50=%s p95=%s p99=%s max=%s\n", mode, len(latencies),
+		percentile(0.50), percentile(0.95), percentile(0.99), latencies[len(latencies)-1].Round(10*time.Microsecond))
+}
+
+func fail(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
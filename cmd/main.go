@@ -15,17 +15,34 @@ import (
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/citadel-corp/belimang/internal/common/db"
+	"github.com/citadel-corp/belimang/internal/common/haversine"
+	"github.com/citadel-corp/belimang/internal/common/jwt"
 	"github.com/citadel-corp/belimang/internal/common/middleware"
+	"github.com/citadel-corp/belimang/internal/common/ratelimit"
+	"github.com/citadel-corp/belimang/internal/common/request"
 	"github.com/citadel-corp/belimang/internal/image"
 	merchantitems "github.com/citadel-corp/belimang/internal/merchant_items"
 	"github.com/citadel-corp/belimang/internal/merchants"
 	"github.com/citadel-corp/belimang/internal/order"
+	"github.com/citadel-corp/belimang/internal/promotions"
+	"github.com/citadel-corp/belimang/internal/search"
 	"github.com/citadel-corp/belimang/internal/user"
 	"github.com/gorilla/mux"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
+// rate limit policies, per user on authorized routes and per IP address on the others
+var (
+	loginRateLimit    = ratelimit.Policy{Name: "login", Limit: 10, Period: time.Minute}
+	registerRateLimit = ratelimit.Policy{Name: "register", Limit: 5, Period: time.Minute}
+	estimateRateLimit = ratelimit.Policy{Name: "estimate", Limit: 30, Period: time.Minute}
+	imageRateLimit    = ratelimit.Policy{Name: "image", Limit: 10, Period: time.Minute}
+)
+
+// rateLimitSweepInterval is how often full buckets are deleted from Postgres.
+const rateLimitSweepInterval = time.Minute
+
 func main() {
 	zerolog.TimeFieldFormat = time.RFC3339
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
@@ -46,24 +63,97 @@ func main() {
 	// 	os.Exit(1)
 	// }
 
+	keyring, err := jwt.LoadKeyring()
+	if err != nil {
+		log.Error().Msg(fmt.Sprintf("Cannot load JWT keys: %v", err))
+		os.Exit(1)
+	}
+	jwt.Use(keyring)
+
+	trustedProxies, err := request.LoadTrustedProxies()
+	if err != nil {
+		log.Error().Msg(fmt.Sprintf("Cannot load trusted proxies: %v", err))
+		os.Exit(1)
+	}
+	request.UseTrustedProxies(trustedProxies)
+
+	rateLimitConfig, err := ratelimit.LoadConfig()
+	if err != nil {
+		log.Error().Msg(fmt.Sprintf("Cannot load rate limit config: %v", err))
+		os.Exit(1)
+	}
+	if rateLimitConfig.Enabled {
+		switch rateLimitConfig.Store {
+		case ratelimit.PostgresStoreName:
+			store := ratelimit.NewPostgresStore(db)
+			go sweepRateLimits(store, rateLimitSweepInterval)
+			middleware.UseRateLimitStore(store)
+		default:
+			middleware.UseRateLimitStore(ratelimit.NewMemoryStore())
+		}
+	}
+
 	// initialize user domain
 	userRepository := user.NewRepository(db)
-	userService := user.NewService(userRepository)
+	userService := user.NewService(db, userRepository)
 	userHandler := user.NewHandler(userService)
+	middleware.UseRevocationList(userService)
 
 	// initialize merchants domain
+	deliveryConfig, err := haversine.LoadDeliveryConfig()
+	if err != nil {
+		log.Error().Msg(fmt.Sprintf("Cannot load delivery config: %v", err))
+		os.Exit(1)
+	}
+	geoIndexConfig, err := merchants.LoadGeoIndexConfig()
+	if err != nil {
+		log.Error().Msg(fmt.Sprintf("Cannot load geo index config: %v", err))
+		os.Exit(1)
+	}
 	merchantRepository := merchants.NewRepository(db)
-	merchantService := merchants.NewService(merchantRepository)
+	if geoIndexConfig.Enabled {
+		indexedRepository, err := merchants.NewIndexedRepository(context.Background(), merchantRepository, geoIndexConfig.Precision)
+		if err != nil {
+			log.Error().Msg(fmt.Sprintf("Cannot load merchants geo index: %v", err))
+			os.Exit(1)
+		}
+		log.Info().Msg(fmt.Sprintf("Loaded %d merchants into the geo index", indexedRepository.Len()))
+		if geoIndexConfig.RefreshInterval > 0 {
+			go refreshGeoIndex(indexedRepository, geoIndexConfig.RefreshInterval)
+		}
+		merchantRepository = indexedRepository
+	}
+	merchantService := merchants.NewService(db, merchantRepository, deliveryConfig)
 	merchantHandler := merchants.NewHandler(merchantService)
 
 	// initialize merchant items domain
 	merchantItemRepository := merchantitems.NewRepository(db)
-	merchantItemService := merchantitems.NewService(merchantItemRepository, merchantRepository)
+	merchantItemService := merchantitems.NewService(db, merchantItemRepository, merchantRepository)
 	merchantItemHandler := merchantitems.NewHandler(merchantItemService)
 
+	// initialize promotions domain
+	promotionRepository := promotions.NewRepository(db)
+	promotionService := promotions.NewService(promotionRepository)
+	promotionHandler := promotions.NewHandler(promotionService)
+
+	// initialize search domain
+	searchRepository := search.NewRepository(db)
+	searchService := search.NewService(searchRepository)
+	searchHandler := search.NewHandler(searchService)
+
 	// initialize order domain
+	pricingConfig, err := order.LoadPricingConfig()
+	if err != nil {
+		log.Error().Msg(fmt.Sprintf("Cannot load pricing config: %v", err))
+		os.Exit(1)
+	}
+	order.CalculatedEstimateTTL, err = order.LoadCalculatedEstimateTTL()
+	if err != nil {
+		log.Error().Msg(fmt.Sprintf("Cannot load calculated estimate TTL: %v", err))
+		os.Exit(1)
+	}
 	orderRepository := order.NewRepository(db)
-	orderService := order.NewService(orderRepository, merchantRepository, merchantItemRepository)
+	orderService := order.NewService(db, orderRepository, pricingConfig, deliveryConfig, promotionRepository, merchantRepository, merchantItemRepository)
 	orderHandler := order.NewHandler(orderService)
 
 	// initialize image domain
@@ -90,28 +180,77 @@ func main() {
 	})
 
 	//
+	r.HandleFunc("/.well-known/jwks.json", userHandler.JWKS).Methods(http.MethodGet)
+	r.HandleFunc("/search", middleware.Authorized(searchHandler.Search)).Methods(http.MethodGet)
 	r.HandleFunc("/merchants/nearby/{lat},{long}", middleware.AuthorizeRole(merchantHandler.ListByDistance, string(user.User))).Methods(http.MethodGet)
 
-	// admin routes
+	// admin routes, also open to the staff granted the permissions
 	ar := r.PathPrefix("/admin").Subrouter()
-	ar.HandleFunc("/register", userHandler.CreateAdmin).Methods(http.MethodPost)
-	ar.HandleFunc("/login", userHandler.LoginUser).Methods(http.MethodPost)
-	ar.HandleFunc("/merchants", middleware.AuthorizeRole(merchantHandler.Create, string(user.Admin))).Methods(http.MethodPost)
-	ar.HandleFunc("/merchants", middleware.AuthorizeRole(merchantHandler.List, string(user.Admin))).Methods(http.MethodGet)
-	ar.HandleFunc("/merchants/{merchantId}/items", middleware.AuthorizeRole(merchantItemHandler.Create, string(user.Admin))).Methods(http.MethodPost)
-	ar.HandleFunc("/merchants/{merchantId}/items", middleware.AuthorizeRole(merchantItemHandler.List, string(user.Admin))).Methods(http.MethodGet)
+	ar.HandleFunc("/register", middleware.RateLimit(userHandler.CreateAdmin, registerRateLimit)).Methods(http.MethodPost)
+	ar.HandleFunc("/login", middleware.RateLimit(userHandler.LoginUser, loginRateLimit)).Methods(http.MethodPost)
+	ar.HandleFunc("/refresh", userHandler.Refresh).Methods(http.MethodPost)
+	ar.HandleFunc("/logout", middleware.Authorized(userHandler.Logout)).Methods(http.MethodPost)
+	ar.HandleFunc("/users", middleware.RequirePermission(userHandler.CreateStaff, "users:write:any")).Methods(http.MethodPost)
+	ar.HandleFunc("/users/{userId}/permissions", middleware.RequirePermission(userHandler.UpdatePermissions, "users:write:any")).Methods(http.MethodPut)
+	ar.HandleFunc("/users/{userId}/unlock", middleware.RequirePermission(userHandler.UnlockUser, "users:write:any")).Methods(http.MethodPost)
+	ar.HandleFunc("/login-blocks", middleware.RequirePermission(userHandler.ListBlockedLogins, "users:read:any")).Methods(http.MethodGet)
+	ar.HandleFunc("/login-blocks/ips/{ip}", middleware.RequirePermission(userHandler.UnblockIP, "users:write:any")).Methods(http.MethodDelete)
+	ar.HandleFunc("/merchants", middleware.RequirePermission(merchantHandler.Create, "merchant:write:any")).Methods(http.MethodPost)
+	ar.HandleFunc("/merchants", middleware.RequirePermission(merchantHandler.List, "merchant:read:any")).Methods(http.MethodGet)
+	ar.HandleFunc("/merchants/{merchantId}", middleware.RequirePermission(merchantHandler.Update, "merchant:write:{merchantId}")).Methods(http.MethodPatch)
+	ar.HandleFunc("/merchants/{merchantId}", middleware.RequirePermission(merchantHandler.Delete, "merchant:write:any")).Methods(http.MethodDelete)
+	ar.HandleFunc("/merchants/{merchantId}/schedule", middleware.RequirePermission(merchantHandler.GetSchedule, "merchant:read:{merchantId}")).Methods(http.MethodGet)
+	ar.HandleFunc("/merchants/{merchantId}/opening-hours", middleware.RequirePermission(merchantHandler.UpdateOpeningHours, "merchant:write:{merchantId}")).Methods(http.MethodPut)
+	ar.HandleFunc("/merchants/{merchantId}/closures", middleware.RequirePermission(merchantHandler.CreateClosure, "merchant:write:{merchantId}")).Methods(http.MethodPost)
+	ar.HandleFunc("/merchants/{merchantId}/closures/{date}", middleware.RequirePermission(merchantHandler.DeleteClosure, "merchant:write:{merchantId}")).Methods(http.MethodDelete)
+	ar.HandleFunc("/merchants/{merchantId}/members", middleware.RequirePermission(merchantHandler.ListMembers, "merchant:read:{merchantId}")).Methods(http.MethodGet)
+	ar.HandleFunc("/merchants/{merchantId}/members", middleware.RequirePermission(merchantHandler.AddMember, "merchant:write:any")).Methods(http.MethodPost)
+	ar.HandleFunc("/merchants/{merchantId}/members/{userId}", middleware.RequirePermission(merchantHandler.RemoveMember, "merchant:write:any")).Methods(http.MethodDelete)
+	ar.HandleFunc("/merchants/{merchantId}/restore", middleware.RequirePermission(merchantHandler.Restore, "merchant:write:any")).Methods(http.MethodPost)
+	ar.HandleFunc("/merchants/{merchantId}/items", middleware.RequirePermission(merchantItemHandler.Create, "merchant:write:{merchantId}")).Methods(http.MethodPost)
+	ar.HandleFunc("/merchants/{merchantId}/items", middleware.RequirePermission(merchantItemHandler.List, "merchant:read:{merchantId}")).Methods(http.MethodGet)
+	ar.HandleFunc("/merchants/{merchantId}/items/{itemId}", middleware.RequirePermission(merchantItemHandler.Update, "merchant:write:{merchantId}")).Methods(http.MethodPatch)
+	ar.HandleFunc("/merchants/{merchantId}/items/{itemId}", middleware.RequirePermission(merchantItemHandler.Delete, "merchant:write:{merchantId}")).Methods(http.MethodDelete)
+	ar.HandleFunc("/merchants/{merchantId}/items/{itemId}/option-groups", middleware.RequirePermission(merchantItemHandler.CreateOptionGroup, "merchant:write:{merchantId}")).Methods(http.MethodPost)
+	ar.HandleFunc("/merchants/{merchantId}/items/{itemId}/option-groups/{optionGroupId}", middleware.RequirePermission(merchantItemHandler.DeleteOptionGroup, "merchant:write:{merchantId}")).Methods(http.MethodDelete)
+	ar.HandleFunc("/promotions", middleware.RequirePermission(promotionHandler.Create, "promotions:write:any")).Methods(http.MethodPost)
+	ar.HandleFunc("/promotions", middleware.RequirePermission(promotionHandler.List, "promotions:read:any")).Methods(http.MethodGet)
+	ar.HandleFunc("/promotions/{promotionId}", middleware.RequirePermission(promotionHandler.Get, "promotions:read:any")).Methods(http.MethodGet)
+	ar.HandleFunc("/promotions/{promotionId}", middleware.RequirePermission(promotionHandler.Update, "promotions:write:any")).Methods(http.MethodPatch)
+	ar.HandleFunc("/promotions/{promotionId}", middleware.RequirePermission(promotionHandler.Delete, "promotions:write:any")).Methods(http.MethodDelete)
+	ar.HandleFunc("/orders/{orderId}/status", middleware.RequirePermission(orderHandler.UpdateOrderStatus, "orders:status:any")).Methods(http.MethodPatch)
+
+	// merchant staff manage the merchants they are members of, the services check the membership
+	mr := r.PathPrefix("/merchant").Subrouter()
+	mr.HandleFunc("/merchants", middleware.Authorized(merchantHandler.ListMine)).Methods(http.MethodGet)
+	mr.HandleFunc("/merchants/{merchantId}", middleware.Authorized(merchantHandler.Update)).Methods(http.MethodPatch)
+	mr.HandleFunc("/merchants/{merchantId}/schedule", middleware.Authorized(merchantHandler.GetSchedule)).Methods(http.MethodGet)
+	mr.HandleFunc("/merchants/{merchantId}/opening-hours", middleware.Authorized(merchantHandler.UpdateOpeningHours)).Methods(http.MethodPut)
+	mr.HandleFunc("/merchants/{merchantId}/closures", middleware.Authorized(merchantHandler.CreateClosure)).Methods(http.MethodPost)
+	mr.HandleFunc("/merchants/{merchantId}/closures/{date}", middleware.Authorized(merchantHandler.DeleteClosure)).Methods(http.MethodDelete)
+	mr.HandleFunc("/merchants/{merchantId}/items", middleware.Authorized(merchantItemHandler.Create)).Methods(http.MethodPost)
+	mr.HandleFunc("/merchants/{merchantId}/items", middleware.Authorized(merchantItemHandler.List)).Methods(http.MethodGet)
+	mr.HandleFunc("/merchants/{merchantId}/items/{itemId}", middleware.Authorized(merchantItemHandler.Update)).Methods(http.MethodPatch)
+	mr.HandleFunc("/merchants/{merchantId}/items/{itemId}", middleware.Authorized(merchantItemHandler.Delete)).Methods(http.MethodDelete)
+	mr.HandleFunc("/merchants/{merchantId}/items/{itemId}/option-groups", middleware.Authorized(merchantItemHandler.CreateOptionGroup)).Methods(http.MethodPost)
+	mr.HandleFunc("/merchants/{merchantId}/items/{itemId}/option-groups/{optionGroupId}", middleware.Authorized(merchantItemHandler.DeleteOptionGroup)).Methods(http.MethodDelete)
+	mr.HandleFunc("/merchants/{merchantId}/orders", middleware.Authorized(orderHandler.ListMerchantOrders)).Methods(http.MethodGet)
+	mr.HandleFunc("/merchants/{merchantId}/orders/{orderId}/status", middleware.Authorized(orderHandler.UpdateMerchantOrderStatus)).Methods(http.MethodPatch)
 
 	ur := r.PathPrefix("/users").Subrouter()
-	ur.HandleFunc("/register", userHandler.CreateNonAdmin).Methods(http.MethodPost)
-	ur.HandleFunc("/login", userHandler.LoginUser).Methods(http.MethodPost)
+	ur.HandleFunc("/register", middleware.RateLimit(userHandler.CreateNonAdmin, registerRateLimit)).Methods(http.MethodPost)
+	ur.HandleFunc("/login", middleware.RateLimit(userHandler.LoginUser, loginRateLimit)).Methods(http.MethodPost)
+	ur.HandleFunc("/refresh", userHandler.Refresh).Methods(http.MethodPost)
+	ur.HandleFunc("/logout", middleware.AuthorizeRole(userHandler.Logout, string(user.User))).Methods(http.MethodPost)
 
-	ur.HandleFunc("/estimate", middleware.AuthorizeRole(orderHandler.CalculateEstimate, string(user.User))).Methods(http.MethodPost)
+	ur.HandleFunc("/estimate", middleware.AuthorizeRole(middleware.RateLimit(orderHandler.CalculateEstimate, estimateRateLimit), string(user.User))).Methods(http.MethodPost)
 	ur.HandleFunc("/orders", middleware.AuthorizeRole(orderHandler.CreateOrder, string(user.User))).Methods(http.MethodPost)
 	ur.HandleFunc("/orders", middleware.AuthorizeRole(orderHandler.SearchOrders, string(user.User))).Methods(http.MethodGet)
+	ur.HandleFunc("/orders/{orderId}/status", middleware.AuthorizeRole(orderHandler.GetOrderStatus, string(user.User))).Methods(http.MethodGet)
 
 	// image routes
 	ir := r.PathPrefix("/image").Subrouter()
-	ir.HandleFunc("", middleware.Authorized(imageHandler.UploadToS3)).Methods(http.MethodPost)
+	ir.HandleFunc("", middleware.Authorized(middleware.RateLimit(imageHandler.UploadToS3, imageRateLimit))).Methods(http.MethodPost)
 
 	httpServer := &http.Server{
 		Addr:    ":8080",
@@ -141,3 +280,30 @@ func main() {
 	}
 	log.Info().Msg("Shutdown complete.")
 }
+
+// refreshGeoIndex reloads the merchants geo index every interval, picking up the
+// writes of the other instances.
+func refreshGeoIndex(indexedRepository *merchants.IndexedRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		if err := indexedRepository.Refresh(ctx); err != nil {
+			log.Error().Msg(fmt.Sprintf("Cannot refresh merchants geo index: %v", err))
+		}
+		cancel()
+	}
+}
+
+// sweepRateLimits deletes the full rate limit buckets every interval.
+func sweepRateLimits(store *ratelimit.PostgresStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		if err := store.Sweep(ctx); err != nil {
+			log.Error().Msg(fmt.Sprintf("Cannot sweep rate limit buckets: %v", err))
+		}
+		cancel()
+	}
+}
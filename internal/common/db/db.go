@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
@@ -20,6 +21,20 @@ type DB struct {
 	sqlDB *sql.DB
 }
 
+// Executor is implemented by both *sql.DB and *sql.Tx, so repositories can run
+// the same queries inside and outside of a transaction.
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Transactor runs f inside a transaction, committing when f returns nil and
+// rolling back otherwise.
+type Transactor interface {
+	StartTx(ctx context.Context, f func(*sql.Tx) error) error
+}
+
 func Connect(dbURL string) (*DB, error) {
 	log.Debug().Msgf("Connecting to %s", dbURL)
 
@@ -43,17 +58,62 @@ func (db *DB) DB() *sql.DB {
 	return db.sqlDB
 }
 
+// Executor returns tx when it is set, and the connection pool otherwise.
+func (db *DB) Executor(tx *sql.Tx) Executor {
+	if tx != nil {
+		return tx
+	}
+	return db.sqlDB
+}
+
+var (
+	afterCommitMu sync.Mutex
+	afterCommit   = make(map[*sql.Tx][]func()) // key: transactions started by StartTx
+)
+
+// AfterCommit runs f once tx commits, and never if it rolls back. f runs right
+// away when tx is nil or was not started by StartTx.
+func AfterCommit(tx *sql.Tx, f func()) {
+	afterCommitMu.Lock()
+	fs, ok := afterCommit[tx]
+	if ok {
+		afterCommit[tx] = append(fs, f)
+	}
+	afterCommitMu.Unlock()
+	if !ok {
+		f()
+	}
+}
+
 func (db *DB) StartTx(ctx context.Context, f func(*sql.Tx) error) error {
 	tx, err := db.sqlDB.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return err
 	}
+	afterCommitMu.Lock()
+	afterCommit[tx] = make([]func(), 0)
+	afterCommitMu.Unlock()
+	defer func() {
+		afterCommitMu.Lock()
+		delete(afterCommit, tx)
+		afterCommitMu.Unlock()
+	}()
+
 	err = f(tx)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
-	return tx.Commit()
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	afterCommitMu.Lock()
+	fs := afterCommit[tx]
+	afterCommitMu.Unlock()
+	for _, f := range fs {
+		f()
+	}
+	return nil
 }
 
 func (db *DB) UpMigration() error {
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Query composes a SELECT out of fragments written with ? placeholders. Every ?
+// is numbered into a positional $n parameter as the fragment is added, so values
+// are only ever bound as arguments and never spliced into the SQL text. Write ??
+// for a literal question mark, e.g. the jsonb ? operator.
+type Query struct {
+	base    string
+	where   []string
+	orderBy []string
+	suffix  string
+	args    []any
+}
+
+// NewQuery starts a query from base, usually a SELECT ... FROM ... without WHERE.
+func NewQuery(base string, args ...any) *Query {
+	q := &Query{}
+	q.base = q.bind(base, args)
+	return q
+}
+
+// Where adds a condition, all conditions being joined with AND. The condition is
+// parenthesized, so it may use OR.
+func (q *Query) Where(condition string, args ...any) *Query {
+	q.where = append(q.where, "("+q.bind(condition, args)+")")
+	return q
+}
+
+// WhereAny adds a condition that column equals one of values, bound as a single
+// array parameter. values must be a slice.
+func (q *Query) WhereAny(column string, values any) *Query {
+	return q.Where(column+" = ANY(?)", values)
+}
+
+// OrderBy adds a sort expression, in order of precedence.
+func (q *Query) OrderBy(expr string, args ...any) *Query {
+	q.orderBy = append(q.orderBy, q.bind(expr, args))
+	return q
+}
+
+// Page limits the result to limit rows after skipping offset rows.
+func (q *Query) Page(limit, offset int) *Query {
+	q.suffix = q.bind(" OFFSET ? LIMIT ?", []any{offset, limit})
+	return q
+}
+
+// Limit limits the result to limit rows, for keyset pagination.
+func (q *Query) Limit(limit int) *Query {
+	q.suffix = q.bind(" LIMIT ?", []any{limit})
+	return q
+}
+
+// Param binds arg and returns its placeholder, for expressions that do not fit
+// Where or OrderBy, e.g. inside a subquery.
+func (q *Query) Param(arg any) string {
+	return q.bind("?", []any{arg})
+}
+
+// Wrap returns a query selecting from q, the SQL of q replacing the first %s of
+// base. The new query keeps the arguments of q and numbers the placeholders of
+// base and of its own fragments after them.
+func (q *Query) Wrap(base string, args ...any) *Query {
+	query, queryArgs := q.Build()
+	w := &Query{args: append([]any(nil), queryArgs...)}
+	w.base = strings.Replace(w.bind(base, args), "%s", query, 1)
+	return w
+}
+
+// Build returns the SQL and its arguments.
+func (q *Query) Build() (string, []any) {
+	var sb strings.Builder
+	sb.WriteString(q.base)
+	if len(q.where) > 0 {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(strings.Join(q.where, " AND "))
+	}
+	if len(q.orderBy) > 0 {
+		sb.WriteString(" ORDER BY ")
+		sb.WriteString(strings.Join(q.orderBy, ", "))
+	}
+	sb.WriteString(q.suffix)
+	return sb.String(), q.args
+}
+
+// bind numbers the placeholders of fragment after the arguments bound so far. It
+// panics when the placeholders and args do not match, which is a programming error.
+func (q *Query) bind(fragment string, args []any) string {
+	var sb strings.Builder
+	n := 0
+	for i := 0; i < len(fragment); i++ {
+		if fragment[i] != '?' {
+			sb.WriteByte(fragment[i])
+			continue
+		}
+		if i+1 < len(fragment) && fragment[i+1] == '?' {
+			sb.WriteByte('?')
+			i++
+			continue
+		}
+		if n == len(args) {
+			panic(fmt.Sprintf("db: %q has more placeholders than the %d args", fragment, len(args)))
+		}
+		q.args = append(q.args, args[n])
+		n++
+		fmt.Fprintf(&sb, "$%d", len(q.args))
+	}
+	if n != len(args) {
+		panic(fmt.Sprintf("db: %q has %d placeholders for %d args", fragment, n, len(args)))
+	}
+	return sb.String()
+}
@@ -0,0 +1,178 @@
+package db
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var hostileInputs = []string{
+	"'; DROP TABLE users; --",
+	`" OR 1=1 --`,
+	"%' OR name ILIKE '%",
+	"$1",
+	"?",
+	"??",
+	`\'); DELETE FROM merchants; --`,
+	"名前' UNION SELECT hashed_password FROM users --",
+}
+
+var placeholderPattern = regexp.MustCompile(`\$(\d+)`)
+
+// assertPlaceholders fails unless sql has the placeholders $1..$len(args), each
+// once. They are numbered in the order they were bound, which is not always the
+// order they appear in, e.g. with Param or Wrap.
+func assertPlaceholders(t *testing.T, sql string, args []any) {
+	t.Helper()
+	matches := placeholderPattern.FindAllStringSubmatch(sql, -1)
+	if len(matches) != len(args) {
+		t.Fatalf("%q has %d placeholders for %d args", sql, len(matches), len(args))
+	}
+	seen := make(map[int]bool)
+	for _, m := range matches {
+		n, _ := strconv.Atoi(m[1])
+		if n < 1 || n > len(args) || seen[n] {
+			t.Fatalf("%q has $%d more than once or without an arg", sql, n)
+		}
+		seen[n] = true
+	}
+}
+
+func TestQueryNeverSplicesInput(t *testing.T) {
+	for _, input := range hostileInputs {
+		t.Run(input, func(t *testing.T) {
+			list := []string{input, "plain"}
+			q := NewQuery("SELECT id, ? AS kind FROM merchants m", input)
+			q.Where("m.name ILIKE ?", "%"+input+"%").
+				Where("m.category = ? OR m.uid = ?", input, input).
+				WhereAny("m.uid", list).
+				OrderBy("m.location <-> point(?, ?)", input, input).
+				OrderBy("m.id")
+			sub := q.Param(input)
+			q.Where("m.id IN (SELECT merchant_id FROM merchant_items WHERE name = " + sub + ")")
+			q.Page(10, 20)
+			w := q.Wrap("SELECT *, ? AS tag FROM (%s) AS t", input)
+			w.Where("t.name <> ?", input).Limit(5)
+
+			sql, args := w.Build()
+			for _, fragment := range []string{"DROP", "DELETE", "UNION", "1=1", "名前"} {
+				if strings.Contains(sql, fragment) {
+					t.Errorf("input reached the SQL: %q", sql)
+				}
+			}
+			if strings.Contains(sql, input) && input != "?" && input != "$1" {
+				t.Errorf("input reached the SQL: %q", sql)
+			}
+			assertPlaceholders(t, sql, args)
+
+			want := []any{
+				input,
+				"%" + input + "%",
+				input, input,
+				list,
+				input,
+				input, input,
+				20, 10,
+				input,
+				input,
+				5,
+			}
+			if !reflect.DeepEqual(args, want) {
+				t.Errorf("args = %#v, want %#v", args, want)
+			}
+		})
+	}
+}
+
+func TestQueryBuild(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    func() *Query
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name:     "base only",
+			query:    func() *Query { return NewQuery("SELECT 1") },
+			wantSQL:  "SELECT 1",
+			wantArgs: nil,
+		},
+		{
+			name: "where, order and page",
+			query: func() *Query {
+				return NewQuery("SELECT id FROM t").Where("a = ?", 1).Where("b = ? OR c = ?", 2, 3).OrderBy("id DESC").Page(10, 0)
+			},
+			wantSQL:  "SELECT id FROM t WHERE (a = $1) AND (b = $2 OR c = $3) ORDER BY id DESC OFFSET $4 LIMIT $5",
+			wantArgs: []any{1, 2, 3, 0, 10},
+		},
+		{
+			name:     "where any",
+			query:    func() *Query { return NewQuery("SELECT id FROM t").WhereAny("uid", []string{"a", "b"}) },
+			wantSQL:  "SELECT id FROM t WHERE (uid = ANY($1))",
+			wantArgs: []any{[]string{"a", "b"}},
+		},
+		{
+			name: "escaped question marks",
+			query: func() *Query {
+				return NewQuery("SELECT id FROM t").Where("tags ?? 'vegan' AND tags ??| ?", []string{"halal"}).Limit(3)
+			},
+			wantSQL:  "SELECT id FROM t WHERE (tags ? 'vegan' AND tags ?| $1) LIMIT $2",
+			wantArgs: []any{[]string{"halal"}, 3},
+		},
+		{
+			name: "wrap renumbers after the inner arguments",
+			query: func() *Query {
+				inner := NewQuery("SELECT id, ? AS d FROM t", 7).Where("a = ?", 1).Page(5, 10)
+				return inner.Wrap("SELECT *, ? AS total FROM (%s) AS x", 99).Where("x.d < ?", 2).OrderBy("x.d").Limit(1)
+			},
+			wantSQL:  "SELECT *, $5 AS total FROM (SELECT id, $1 AS d FROM t WHERE (a = $2) OFFSET $3 LIMIT $4) AS x WHERE (x.d < $6) ORDER BY x.d LIMIT $7",
+			wantArgs: []any{7, 1, 10, 5, 99, 2, 1},
+		},
+		{
+			name: "param numbers in order",
+			query: func() *Query {
+				q := NewQuery("SELECT id FROM t").Where("a = ?", 1)
+				p := q.Param("x")
+				return q.Where("b IN (SELECT b FROM u WHERE c = "+p+")").Where("d = ?", 2)
+			},
+			wantSQL:  "SELECT id FROM t WHERE (a = $1) AND (b IN (SELECT b FROM u WHERE c = $2)) AND (d = $3)",
+			wantArgs: []any{1, "x", 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args := tt.query().Build()
+			if sql != tt.wantSQL {
+				t.Errorf("Build() sql = %q, want %q", sql, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("Build() args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestQueryPanicsOnPlaceholderMismatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func()
+	}{
+		{name: "more placeholders than args", build: func() { NewQuery("SELECT id FROM t").Where("a = ? AND b = ?", 1) }},
+		{name: "more args than placeholders", build: func() { NewQuery("SELECT id FROM t").Where("a = ?", 1, 2) }},
+		{name: "args without placeholder", build: func() { NewQuery("SELECT id FROM t", 1) }},
+		{name: "escaped placeholder with arg", build: func() { NewQuery("SELECT id FROM t").Where("tags ?? 'a'", 1) }},
+		{name: "wrap base without arg", build: func() { NewQuery("SELECT 1").Wrap("SELECT * FROM (%s) AS x WHERE y = ?") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("did not panic")
+				}
+			}()
+			tt.build()
+		})
+	}
+}
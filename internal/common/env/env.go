@@ -0,0 +1,81 @@
+// Package env reads configuration from environment variables, for the Load*
+// config functions run at startup. An unset or empty variable leaves the
+// fallback, and a malformed one fails with the variable name prefixed to the
+// error.
+package env
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+func String(key string, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func Bool(key string, fallback bool) (bool, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fallback, fmt.Errorf("%s: %w", key, err)
+	}
+	return b, nil
+}
+
+func Int(key string, fallback int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return fallback, fmt.Errorf("%s: %w", key, err)
+	}
+	return i, nil
+}
+
+func Float(key string, fallback float64) (float64, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fallback, fmt.Errorf("%s: %w", key, err)
+	}
+	return f, nil
+}
+
+// Duration parses a Go duration, e.g. "15m".
+func Duration(key string, fallback time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fallback, fmt.Errorf("%s: %w", key, err)
+	}
+	return d, nil
+}
+
+// JSON unmarshals the variable into dst, leaving dst as is when it is unset.
+func JSON(key string, dst any) error {
+	v := os.Getenv(key)
+	if v == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(v), dst); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	return nil
+}
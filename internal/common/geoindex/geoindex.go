@@ -0,0 +1,314 @@
+// Package geoindex keeps points in memory on a geohash grid and answers nearest
+// and radius queries over them without a database round trip.
+package geoindex
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"sync"
+)
+
+// EarthRadius is the radius in meters used by the earthdistance extension, so
+// distances agree with the ones computed in Postgres.
+const EarthRadius = 6378168.0
+
+// DefaultPrecision is a geohash length of 6, cells of about 1.2 km by 0.6 km.
+const DefaultPrecision = 6
+
+// Point is a value located at Lat and Lng, identified by ID.
+type Point[T any] struct {
+	ID    string
+	Lat   float64
+	Lng   float64
+	Value T
+}
+
+// Result is a point found by a query, Distance meters away from the queried location.
+type Result[T any] struct {
+	Point[T]
+	Distance float64
+}
+
+// cell is a geohash cell as its column and row on the grid, which makes the
+// neighbouring cells a matter of arithmetic.
+type cell struct {
+	x, y int
+}
+
+// Index holds points on the grid of the geohash cells of a given precision. It is
+// safe for concurrent use.
+type Index[T any] struct {
+	mu         sync.RWMutex
+	columns    int
+	rows       int
+	cellWidth  float64 // degrees of longitude
+	cellHeight float64 // degrees of latitude
+	points     map[string]Point[T]
+	cells      map[cell]map[string]Point[T]
+	version    uint64            // counts the calls to Put and Remove
+	written    map[string]uint64 // the version of the last Put or Remove of an id
+}
+
+// New returns an empty index on the cells of geohashes of length precision,
+// between 1 and 12. Other precisions fall back to DefaultPrecision.
+func New[T any](precision int) *Index[T] {
+	if precision < 1 || precision > 12 {
+		precision = DefaultPrecision
+	}
+	// geohashes interleave 5 bits per character starting with longitude, so
+	// longitude gets the odd bit
+	bits := 5 * precision
+	lngBits, latBits := (bits+1)/2, bits/2
+	idx := &Index[T]{
+		columns: 1 << lngBits,
+		rows:    1 << latBits,
+	}
+	idx.cellWidth = 360 / float64(idx.columns)
+	idx.cellHeight = 180 / float64(idx.rows)
+	idx.points = make(map[string]Point[T])
+	idx.cells = make(map[cell]map[string]Point[T])
+	idx.written = make(map[string]uint64)
+	return idx
+}
+
+// Len returns the number of points in the index.
+func (idx *Index[T]) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.points)
+}
+
+// Get returns the point with the id.
+func (idx *Index[T]) Get(id string) (Point[T], bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	p, ok := idx.points[id]
+	return p, ok
+}
+
+// Put adds the point, replacing the one with the same id.
+func (idx *Index[T]) Put(p Point[T]) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.version++
+	idx.written[p.ID] = idx.version
+	idx.remove(p.ID)
+	idx.put(p)
+}
+
+// Remove removes the point with the id and reports whether there was one.
+func (idx *Index[T]) Remove(id string) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.version++
+	idx.written[id] = idx.version
+	return idx.remove(id)
+}
+
+// Version returns the number of calls to Put and Remove so far. Loading points
+// after reading it and passing both to ReplaceSince keeps the writes that race
+// with the load.
+func (idx *Index[T]) Version() uint64 {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.version
+}
+
+// Replace swaps every point of the index for points at once, so queries never
+// see a partially loaded index.
+func (idx *Index[T]) Replace(points []Point[T]) {
+	idx.ReplaceSince(math.MaxUint64, points)
+}
+
+// ReplaceSince is Replace for points loaded after Version returned since: the
+// ids put or removed after that keep their point, or their absence, as points
+// may predate those writes. Calls to ReplaceSince must not overlap.
+func (idx *Index[T]) ReplaceSince(since uint64, points []Point[T]) {
+	fresh := &Index[T]{
+		columns:    idx.columns,
+		rows:       idx.rows,
+		cellWidth:  idx.cellWidth,
+		cellHeight: idx.cellHeight,
+		points:     make(map[string]Point[T], len(points)),
+		cells:      make(map[cell]map[string]Point[T]),
+	}
+	for _, p := range points {
+		fresh.remove(p.ID)
+		fresh.put(p)
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for id, version := range idx.written {
+		if version <= since {
+			// points already has this write, and a later call has a later since
+			delete(idx.written, id)
+			continue
+		}
+		fresh.remove(id)
+		if p, ok := idx.points[id]; ok {
+			fresh.put(p)
+		}
+	}
+	idx.points, idx.cells = fresh.points, fresh.cells
+}
+
+// Nearest returns up to k points accepted by accept, nearest to lat and lng
+// first, ties broken by id. Points further than radius meters are left out,
+// unless radius is zero. accept may be nil to accept every point.
+func (idx *Index[T]) Nearest(lat, lng float64, k int, radius float64, accept func(Result[T]) bool) []Result[T] {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	if k <= 0 || len(idx.points) == 0 {
+		return []Result[T]{}
+	}
+
+	found := make([]Result[T], 0)
+	consider := func(p Point[T]) {
+		r := Result[T]{Point: p, Distance: Distance(lat, lng, p.Lat, p.Lng)}
+		if radius > 0 && r.Distance > radius {
+			return
+		}
+		if accept == nil || accept(r) {
+			found = append(found, r)
+		}
+	}
+
+	center := idx.cellOf(lat, lng)
+	for ring := 0; ; ring++ {
+		// past the point where a ring has more cells than the index has points, or
+		// wraps around the globe, looking at every point is cheaper and exact
+		if 8*ring > len(idx.points) || 2*ring+1 > idx.columns {
+			found = found[:0]
+			for _, p := range idx.points {
+				consider(p)
+			}
+			sortResults(found)
+			break
+		}
+		idx.visitRing(center, ring, func(c cell) {
+			for _, p := range idx.cells[c] {
+				consider(p)
+			}
+		})
+
+		// any point outside the rings visited so far is at least bound away
+		bound := idx.ringBound(lat, ring)
+		if len(found) >= k {
+			sortResults(found)
+			if found[k-1].Distance <= bound {
+				break
+			}
+		}
+		if radius > 0 && bound >= radius || ring >= idx.rows {
+			sortResults(found)
+			break
+		}
+	}
+	if len(found) > k {
+		found = found[:k]
+	}
+	return found
+}
+
+// Within returns every point accepted by accept within radius meters of lat and
+// lng, nearest first.
+func (idx *Index[T]) Within(lat, lng, radius float64, accept func(Result[T]) bool) []Result[T] {
+	if radius <= 0 {
+		return []Result[T]{}
+	}
+	return idx.Nearest(lat, lng, math.MaxInt, radius, accept)
+}
+
+// Distance returns the great circle distance in meters between two locations.
+func Distance(lat1, lng1, lat2, lng2 float64) float64 {
+	phi1, phi2 := radians(lat1), radians(lat2)
+	dPhi, dLambda := phi2-phi1, radians(lng2-lng1)
+	h := hav(dPhi) + math.Cos(phi1)*math.Cos(phi2)*hav(dLambda)
+	return 2 * EarthRadius * math.Asin(math.Sqrt(min(1, h)))
+}
+
+func (idx *Index[T]) put(p Point[T]) {
+	c := idx.cellOf(p.Lat, p.Lng)
+	if idx.cells[c] == nil {
+		idx.cells[c] = make(map[string]Point[T])
+	}
+	idx.cells[c][p.ID] = p
+	idx.points[p.ID] = p
+}
+
+func (idx *Index[T]) remove(id string) bool {
+	p, ok := idx.points[id]
+	if !ok {
+		return false
+	}
+	c := idx.cellOf(p.Lat, p.Lng)
+	delete(idx.cells[c], id)
+	if len(idx.cells[c]) == 0 {
+		delete(idx.cells, c)
+	}
+	delete(idx.points, id)
+	return true
+}
+
+func (idx *Index[T]) cellOf(lat, lng float64) cell {
+	x := int((lng + 180) / idx.cellWidth)
+	y := int((lat + 90) / idx.cellHeight)
+	return cell{x: min(max(x, 0), idx.columns-1), y: min(max(y, 0), idx.rows-1)}
+}
+
+// visitRing calls visit with the cells ring cells away from center, wrapping
+// around the antimeridian and stopping at the poles.
+func (idx *Index[T]) visitRing(center cell, ring int, visit func(cell)) {
+	for dy := -ring; dy <= ring; dy++ {
+		y := center.y + dy
+		if y < 0 || y >= idx.rows {
+			continue
+		}
+		step := 1
+		if dy != -ring && dy != ring {
+			// only the first and last columns of the inner rows are on the ring
+			step = 2 * ring
+		}
+		for dx := -ring; dx <= ring; dx += step {
+			x := ((center.x+dx)%idx.columns + idx.columns) % idx.columns
+			visit(cell{x: x, y: y})
+			if ring == 0 {
+				break
+			}
+		}
+	}
+}
+
+// ringBound returns a lower bound of the distance from a location at lat, in
+// the center cell, to any point outside the rings up to ring.
+func (idx *Index[T]) ringBound(lat float64, ring int) float64 {
+	if ring == 0 {
+		return 0
+	}
+	// leaving through the top or bottom takes at least ring cells of latitude
+	latBound := EarthRadius * radians(float64(ring)*idx.cellHeight)
+	// leaving through the sides takes at least ring cells of longitude, which are
+	// narrowest at the latitude of the rings closest to a pole
+	maxLat := math.Min(90, math.Abs(lat)+float64(ring+1)*idx.cellHeight)
+	dLambda := math.Min(math.Pi, radians(float64(ring)*idx.cellWidth))
+	lngBound := 2 * EarthRadius * math.Asin(math.Cos(radians(maxLat))*math.Sin(dLambda/2))
+	return math.Min(latBound, lngBound)
+}
+
+func sortResults[T any](results []Result[T]) {
+	slices.SortFunc(results, func(a, b Result[T]) int {
+		if c := cmp.Compare(a.Distance, b.Distance); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func hav(theta float64) float64 {
+	return (1 - math.Cos(theta)) / 2
+}
@@ -0,0 +1,200 @@
+package geoindex
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// linearNearest is Nearest by looking at every point.
+func linearNearest(points []Point[int], lat, lng float64, k int, radius float64, accept func(Result[int]) bool) []Result[int] {
+	found := make([]Result[int], 0)
+	for _, p := range points {
+		r := Result[int]{Point: p, Distance: Distance(lat, lng, p.Lat, p.Lng)}
+		if radius > 0 && r.Distance > radius {
+			continue
+		}
+		if accept == nil || accept(r) {
+			found = append(found, r)
+		}
+	}
+	sortResults(found)
+	if len(found) > k {
+		found = found[:k]
+	}
+	return found
+}
+
+func resultIDs(results []Result[int]) []string {
+	ids := make([]string, 0, len(results))
+	for _, r := range results {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func newIndex(precision int, points []Point[int]) *Index[int] {
+	idx := New[int](precision)
+	idx.Replace(points)
+	return idx
+}
+
+func TestNearestMatchesLinearScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	// clustered around Jakarta, where the grid cells matter, and spread over
+	// the globe, where the scan falls back to every point
+	areas := []struct {
+		name           string
+		lat, lng, span float64
+	}{
+		{name: "jakarta", lat: -6.2, lng: 106.8, span: 0.5},
+		{name: "globe", lat: 0, lng: 0, span: 180},
+	}
+	for _, area := range areas {
+		points := make([]Point[int], 0, 500)
+		for i := range 500 {
+			points = append(points, Point[int]{
+				ID:    fmt.Sprintf("p%03d", i),
+				Lat:   math.Max(-90, math.Min(90, area.lat+(rng.Float64()-0.5)*area.span)),
+				Lng:   math.Mod(area.lng+(rng.Float64()-0.5)*2*area.span+540, 360) - 180,
+				Value: i,
+			})
+		}
+		for _, precision := range []int{3, 6} {
+			idx := newIndex(precision, points)
+			for i := range 50 {
+				lat := math.Max(-90, math.Min(90, area.lat+(rng.Float64()-0.5)*area.span))
+				lng := math.Mod(area.lng+(rng.Float64()-0.5)*2*area.span+540, 360) - 180
+				k := 1 + rng.Intn(20)
+				radius := 0.0
+				if i%2 == 1 {
+					radius = rng.Float64() * 20000
+				}
+				even := func(r Result[int]) bool { return r.Value%2 == 0 }
+				for _, accept := range []func(Result[int]) bool{nil, even} {
+					name := fmt.Sprintf("%s precision %d query %d", area.name, precision, i)
+					t.Run(name, func(t *testing.T) {
+						want := resultIDs(linearNearest(points, lat, lng, k, radius, accept))
+						got := resultIDs(idx.Nearest(lat, lng, k, radius, accept))
+						if !slices.Equal(got, want) {
+							t.Errorf("Nearest(%v, %v, %d, %v) = %v, want %v", lat, lng, k, radius, got, want)
+						}
+					})
+				}
+			}
+		}
+	}
+}
+
+func TestNearestAcrossAntimeridian(t *testing.T) {
+	points := []Point[int]{
+		{ID: "west", Lat: 0, Lng: -179.99},
+		{ID: "east", Lat: 0, Lng: 179.9},
+		{ID: "far", Lat: 0, Lng: 170},
+	}
+	idx := newIndex(DefaultPrecision, points)
+
+	got := resultIDs(idx.Nearest(0, 179.99, 2, 0, nil))
+	if want := []string{"west", "east"}; !slices.Equal(got, want) {
+		t.Errorf("Nearest = %v, want %v", got, want)
+	}
+	// about 2.2 km away over the antimeridian
+	got = resultIDs(idx.Within(0, 179.99, 3000, nil))
+	if want := []string{"west"}; !slices.Equal(got, want) {
+		t.Errorf("Within = %v, want %v", got, want)
+	}
+}
+
+func TestNearestAroundPoles(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lng float64
+		points   []Point[int]
+		want     []string
+	}{
+		{
+			name: "across the north pole",
+			lat:  89.99, lng: 0,
+			points: []Point[int]{
+				{ID: "other side", Lat: 89.99, Lng: 180},
+				{ID: "same side", Lat: 89.9, Lng: 0},
+			},
+			want: []string{"other side", "same side"},
+		},
+		{
+			name: "at the south pole",
+			lat:  -90, lng: 0,
+			points: []Point[int]{
+				{ID: "near", Lat: -89.99, Lng: 90},
+				{ID: "far", Lat: -89, Lng: -45},
+				{ID: "north", Lat: 10, Lng: 0},
+			},
+			want: []string{"near", "far"},
+		},
+		{
+			name: "on the pole",
+			lat:  89.9, lng: 45,
+			points: []Point[int]{
+				{ID: "pole", Lat: 90, Lng: 0},
+				{ID: "equator", Lat: 0, Lng: 45},
+			},
+			want: []string{"pole", "equator"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := newIndex(DefaultPrecision, tt.points)
+			got := resultIDs(idx.Nearest(tt.lat, tt.lng, len(tt.want), 0, nil))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Nearest = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplaceSinceKeepsLaterWrites(t *testing.T) {
+	idx := newIndex(DefaultPrecision, []Point[int]{
+		{ID: "moved", Lat: -6.2, Lng: 106.8, Value: 1},
+		{ID: "deleted", Lat: -6.2, Lng: 106.8},
+		{ID: "kept", Lat: -6.2, Lng: 106.8},
+	})
+	idx.Put(Point[int]{ID: "old", Lat: -6.2, Lng: 106.8})
+
+	since := idx.Version()
+	// the writes committing while the points load
+	idx.Put(Point[int]{ID: "moved", Lat: -6.3, Lng: 106.9, Value: 2})
+	idx.Remove("deleted")
+	idx.Put(Point[int]{ID: "created", Lat: -6.2, Lng: 106.8})
+	// loaded before those writes, and after "old" went away
+	idx.ReplaceSince(since, []Point[int]{
+		{ID: "moved", Lat: -6.2, Lng: 106.8, Value: 1},
+		{ID: "deleted", Lat: -6.2, Lng: 106.8},
+		{ID: "kept", Lat: -6.2, Lng: 106.8},
+	})
+
+	if p, ok := idx.Get("moved"); !ok || p.Value != 2 {
+		t.Errorf("moved = %v, %v, want the later put", p, ok)
+	}
+	for _, id := range []string{"deleted", "old"} {
+		if _, ok := idx.Get(id); ok {
+			t.Errorf("%s is in the index", id)
+		}
+	}
+	for _, id := range []string{"kept", "created"} {
+		if _, ok := idx.Get(id); !ok {
+			t.Errorf("%s is not in the index", id)
+		}
+	}
+	got := resultIDs(idx.Nearest(-6.3, 106.9, 1, 0, nil))
+	if want := []string{"moved"}; !slices.Equal(got, want) {
+		t.Errorf("Nearest = %v, want %v", got, want)
+	}
+
+	// a later reload takes over from the writes it has seen
+	idx.ReplaceSince(idx.Version(), []Point[int]{{ID: "kept", Lat: -6.2, Lng: 106.8}})
+	if idx.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", idx.Len())
+	}
+}
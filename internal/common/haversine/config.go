@@ -0,0 +1,140 @@
+package haversine
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/citadel-corp/belimang/internal/common/env"
+	"github.com/citadel-corp/belimang/internal/merchants"
+)
+
+// DefaultMaxRadius keeps the original limit of a 3 km² delivery circle.
+var DefaultMaxRadius = math.Sqrt(3 / math.Pi) // km
+
+// SpeedProfile overrides the courier speed between StartHour (inclusive) and
+// EndHour (exclusive), e.g. during rush hour. Profiles may wrap midnight.
+type SpeedProfile struct {
+	StartHour int     `json:"startHour"`
+	EndHour   int     `json:"endHour"`
+	SpeedInMS float64 `json:"speedInMS"`
+}
+
+// DeliveryConfig drives the delivery radius check and the ETA.
+type DeliveryConfig struct {
+	MaxRadius                  float64 // km between the user and any merchant
+	CategoryMaxRadius          map[merchants.MerchantCategory]float64
+	CourierSpeedInMS           float64
+	SpeedProfiles              []SpeedProfile
+	PreparationMinutes         int
+	CategoryPreparationMinutes map[merchants.MerchantCategory]int
+	Location                   *time.Location // time zone the speed profiles are expressed in
+}
+
+func DefaultDeliveryConfig() DeliveryConfig {
+	return DeliveryConfig{
+		MaxRadius:                  DefaultMaxRadius,
+		CategoryMaxRadius:          make(map[merchants.MerchantCategory]float64),
+		CourierSpeedInMS:           11.11,
+		SpeedProfiles:              make([]SpeedProfile, 0),
+		CategoryPreparationMinutes: make(map[merchants.MerchantCategory]int),
+		Location:                   time.UTC,
+	}
+}
+
+// LoadDeliveryConfig reads the delivery configuration from env. The per category
+// envs are JSON objects keyed by merchant category, e.g. {"LargeRestaurant": 2.5},
+// and DELIVERY_SPEED_PROFILES is a JSON array of speed profiles.
+func LoadDeliveryConfig() (DeliveryConfig, error) {
+	var (
+		cfg = DefaultDeliveryConfig()
+		err error
+	)
+	if cfg.MaxRadius, err = env.Float("DELIVERY_MAX_RADIUS_KM", cfg.MaxRadius); err != nil {
+		return cfg, err
+	}
+	if cfg.CourierSpeedInMS, err = env.Float("DELIVERY_COURIER_SPEED_MS", cfg.CourierSpeedInMS); err != nil {
+		return cfg, err
+	}
+	if cfg.PreparationMinutes, err = env.Int("DELIVERY_PREPARATION_MINUTES", cfg.PreparationMinutes); err != nil {
+		return cfg, err
+	}
+	if err = env.JSON("DELIVERY_CATEGORY_MAX_RADIUS_KM", &cfg.CategoryMaxRadius); err != nil {
+		return cfg, err
+	}
+	if err = env.JSON("DELIVERY_CATEGORY_PREPARATION_MINUTES", &cfg.CategoryPreparationMinutes); err != nil {
+		return cfg, err
+	}
+	if err = env.JSON("DELIVERY_SPEED_PROFILES", &cfg.SpeedProfiles); err != nil {
+		return cfg, err
+	}
+	if v := env.String("DELIVERY_TIMEZONE", ""); v != "" {
+		if cfg.Location, err = time.LoadLocation(v); err != nil {
+			return cfg, fmt.Errorf("DELIVERY_TIMEZONE: %w", err)
+		}
+	}
+	return cfg, cfg.validate()
+}
+
+func (c DeliveryConfig) validate() error {
+	if c.MaxRadius <= 0 {
+		return errors.New("DELIVERY_MAX_RADIUS_KM must be positive")
+	}
+	for category, radius := range c.CategoryMaxRadius {
+		if radius <= 0 {
+			return fmt.Errorf("DELIVERY_CATEGORY_MAX_RADIUS_KM: radius of %s must be positive", category)
+		}
+	}
+	if c.CourierSpeedInMS <= 0 {
+		return errors.New("DELIVERY_COURIER_SPEED_MS must be positive")
+	}
+	for _, profile := range c.SpeedProfiles {
+		if profile.StartHour < 0 || profile.StartHour > 23 || profile.EndHour < 0 || profile.EndHour > 24 {
+			return fmt.Errorf("DELIVERY_SPEED_PROFILES: hours must be between 0 and 24, got %d-%d", profile.StartHour, profile.EndHour)
+		}
+		if profile.SpeedInMS <= 0 {
+			return errors.New("DELIVERY_SPEED_PROFILES: speed must be positive")
+		}
+	}
+	if c.PreparationMinutes < 0 {
+		return errors.New("DELIVERY_PREPARATION_MINUTES must not be negative")
+	}
+	for category, minutes := range c.CategoryPreparationMinutes {
+		if minutes < 0 {
+			return fmt.Errorf("DELIVERY_CATEGORY_PREPARATION_MINUTES: minutes of %s must not be negative", category)
+		}
+	}
+	return nil
+}
+
+// MaxRadiusFor returns the delivery radius in kilometers for the merchant category.
+func (c DeliveryConfig) MaxRadiusFor(category merchants.MerchantCategory) float64 {
+	if radius, ok := c.CategoryMaxRadius[category]; ok {
+		return radius
+	}
+	return c.MaxRadius
+}
+
+// PreparationMinutesFor returns how long merchants of the category need to prepare an order.
+func (c DeliveryConfig) PreparationMinutesFor(category merchants.MerchantCategory) int {
+	if minutes, ok := c.CategoryPreparationMinutes[category]; ok {
+		return minutes
+	}
+	return c.PreparationMinutes
+}
+
+// SpeedAt returns the courier speed in m/s at t, the first matching profile winning.
+func (c DeliveryConfig) SpeedAt(t time.Time) float64 {
+	hour := t.In(c.Location).Hour()
+	for _, profile := range c.SpeedProfiles {
+		if profile.StartHour <= profile.EndHour {
+			if hour >= profile.StartHour && hour < profile.EndHour {
+				return profile.SpeedInMS
+			}
+		} else if hour >= profile.StartHour || hour < profile.EndHour {
+			return profile.SpeedInMS
+		}
+	}
+	return c.CourierSpeedInMS
+}
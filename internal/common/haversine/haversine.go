@@ -1,49 +1,67 @@
 package haversine
 
 import (
+	"fmt"
 	"math"
+	"time"
 
 	"github.com/LucaTheHacker/go-haversine"
 	"github.com/citadel-corp/belimang/internal/merchants"
 )
 
-func CalculateDeliveryTime(lat, lng float64, startingMerchantID string, merchantList []*merchants.Merchants) (int, error) {
-	var startingPoint haversine.Coordinates
+// CalculateDeliveryTime plans the pickup route and returns it together with the
+// delivery time in minutes for a courier leaving at departure. The delivery time
+// includes the longest preparation time of the merchants, which cook in parallel.
+func (c DeliveryConfig) CalculateDeliveryTime(lat, lng float64, startingMerchantID string, merchantList []*merchants.Merchants, departure time.Time) (*Route, int, error) {
 	endPoint := haversine.NewCoordinates(lat, lng)
-	merchantPoints := make([]haversine.Coordinates, 0)
-	visited := make(map[string]bool) // string: merchant id, bool: has visited
-	merchantListToVisit := make([]*merchants.Merchants, 0)
-	for _, merchant := range merchantList {
-		if merchant.UID == startingMerchantID {
-			startingPoint = haversine.NewCoordinates(merchant.Lat, merchant.Lng)
-		} else {
-			merchantPoints = append(merchantPoints, haversine.NewCoordinates(merchant.Lat, merchant.Lng))
-			merchantListToVisit = append(merchantListToVisit, merchant)
-			visited[merchant.UID] = false
-		}
+	err := c.CheckDistance(endPoint, merchantList)
+	if err != nil {
+		return nil, 0, err
 	}
-	if IsMoreThan3KM2(endPoint, merchantList) {
-		return 0, ErrDistanceTooFar
+	route := PlanRoute(lat, lng, startingMerchantID, merchantList)
+	preparationMinutes := 0
+	for _, merchant := range merchantList {
+		preparationMinutes = max(preparationMinutes, c.PreparationMinutesFor(merchant.Category))
 	}
+	return route, preparationMinutes + int(c.TravelMinutes(route.TotalDistance, departure)), nil
+}
 
-	numPoints := len(merchantListToVisit)
-	i := 0
-	currDist := 0.0
-	point := startingPoint
-	for i < numPoints {
-		points := GetPointsToCalculate(merchantListToVisit, visited)
-		merchant, dist := NearestNeighbor(point, points)
-		visited[merchant.UID] = true
-		currDist += dist
-		point = haversine.NewCoordinates(merchant.Lat, merchant.Lng)
-		i += 1
+// TravelMinutes returns how long the courier leaving at departure needs to travel
+// distance kilometers.
+func (c DeliveryConfig) TravelMinutes(distance float64, departure time.Time) float64 {
+	timeSecond := distance * 1000 / c.SpeedAt(departure)
+	return timeSecond / 60
+}
+
+// EstimateMinutes estimates the delivery time in minutes of an order from a single
+// merchant of the category, distance meters away, for a courier leaving at departure.
+func (c DeliveryConfig) EstimateMinutes(category merchants.MerchantCategory, distance float64, departure time.Time) int {
+	return c.PreparationMinutesFor(category) + int(c.TravelMinutes(distance/1000, departure))
+}
+
+// CheckDistance reports the merchant exceeding the delivery radius of its category
+// by the most, if any.
+func (c DeliveryConfig) CheckDistance(point haversine.Coordinates, merchantList []*merchants.Merchants) error {
+	var (
+		farthest            *merchants.Merchants
+		farthestDist, limit float64
+		worstExcess         = 0.0
+	)
+	for _, merchant := range merchantList {
+		d := haversine.Distance(
+			point,
+			haversine.NewCoordinates(merchant.Lat, merchant.Lng),
+		).Kilometers()
+		radius := c.MaxRadiusFor(merchant.Category)
+		if excess := d - radius; excess > worstExcess {
+			worstExcess = excess
+			farthest, farthestDist, limit = merchant, d, radius
+		}
 	}
-	lastDist := haversine.Distance(point, endPoint).Kilometers()
-	currDist += lastDist
-	speedInMS := 11.11 // m/s
-	currDist *= 1000   // convert to meter
-	timeSecond := currDist / speedInMS
-	return int(timeSecond / 60), nil
+	if farthest != nil {
+		return fmt.Errorf("%w: merchant %s is %.2f km away, the limit is %.2f km", ErrDistanceTooFar, farthest.UID, farthestDist, limit)
+	}
+	return nil
 }
 
 func NearestNeighbor(point haversine.Coordinates, merchantList []*merchants.Merchants) (*merchants.Merchants, float64) {
@@ -78,15 +96,6 @@ func FarthestNeighbor(point haversine.Coordinates, merchantList []*merchants.Mer
 	return res, dist
 }
 
-func IsMoreThan3KM2(point haversine.Coordinates, merchantList []*merchants.Merchants) bool {
-	_, dist := FarthestNeighbor(point, merchantList)
-	circleArea := math.Pi * dist * dist
-	if circleArea > 3 {
-		return true
-	}
-	return false
-}
-
 func GetPointsToCalculate(merchantList []*merchants.Merchants, visited map[string]bool) []*merchants.Merchants {
 	res := make([]*merchants.Merchants, 0)
 	for _, merchant := range merchantList {
@@ -0,0 +1,206 @@
+package haversine
+
+import (
+	"math"
+
+	"github.com/LucaTheHacker/go-haversine"
+	"github.com/citadel-corp/belimang/internal/merchants"
+)
+
+// MaxExactRouteMerchants is the largest number of merchants, excluding the starting
+// merchant, for which the route is solved exactly with Held-Karp. Larger orders fall
+// back to nearest neighbor improved with 2-opt.
+var MaxExactRouteMerchants = 12
+
+// Route is the order in which merchants are visited before delivering to the user.
+// Legs[i] is the distance in kilometers from Stops[i] to the next stop, the last leg
+// being the one from the last merchant to the user.
+type Route struct {
+	Stops         []*merchants.Merchants
+	Legs          []float64
+	TotalDistance float64
+}
+
+// PlanRoute finds the shortest route starting at startingMerchantID, visiting every
+// merchant in merchantList once and ending at the user location (lat, lng).
+func PlanRoute(lat, lng float64, startingMerchantID string, merchantList []*merchants.Merchants) *Route {
+	var startingMerchant *merchants.Merchants
+	merchantListToVisit := make([]*merchants.Merchants, 0)
+	for _, merchant := range merchantList {
+		if merchant.UID == startingMerchantID && startingMerchant == nil {
+			startingMerchant = merchant
+		} else {
+			merchantListToVisit = append(merchantListToVisit, merchant)
+		}
+	}
+	if startingMerchant == nil {
+		return &Route{}
+	}
+	endPoint := haversine.NewCoordinates(lat, lng)
+
+	// points[0] is the starting merchant, points[len(points)-1] is the user
+	points := make([]haversine.Coordinates, 0, len(merchantListToVisit)+2)
+	points = append(points, haversine.NewCoordinates(startingMerchant.Lat, startingMerchant.Lng))
+	for _, merchant := range merchantListToVisit {
+		points = append(points, haversine.NewCoordinates(merchant.Lat, merchant.Lng))
+	}
+	points = append(points, endPoint)
+	dist := distanceMatrix(points)
+
+	var order []int // indexes into merchantListToVisit
+	if len(merchantListToVisit) <= MaxExactRouteMerchants {
+		order = heldKarp(dist)
+	} else {
+		order = nearestNeighborOrder(startingMerchant, merchantListToVisit)
+		order = twoOpt(dist, order)
+	}
+
+	route := &Route{
+		Stops: []*merchants.Merchants{startingMerchant},
+		Legs:  make([]float64, 0, len(order)+1),
+	}
+	prev := 0
+	for _, i := range order {
+		route.Stops = append(route.Stops, merchantListToVisit[i])
+		route.Legs = append(route.Legs, dist[prev][i+1])
+		prev = i + 1
+	}
+	route.Legs = append(route.Legs, dist[prev][len(points)-1])
+	for _, leg := range route.Legs {
+		route.TotalDistance += leg
+	}
+	return route
+}
+
+func distanceMatrix(points []haversine.Coordinates) [][]float64 {
+	dist := make([][]float64, len(points))
+	for i := range points {
+		dist[i] = make([]float64, len(points))
+		for j := range points {
+			if i != j {
+				dist[i][j] = haversine.Distance(points[i], points[j]).Kilometers()
+			}
+		}
+	}
+	return dist
+}
+
+// heldKarp solves the open path from dist[0] to dist[n+1] visiting 1..n exactly.
+// It returns the visit order as zero based merchant indexes (point index - 1).
+func heldKarp(dist [][]float64) []int {
+	n := len(dist) - 2
+	if n == 0 {
+		return []int{}
+	}
+	end := n + 1
+	full := 1<<n - 1
+
+	// cost[mask][j]: shortest path from the start visiting mask, ending at merchant j
+	cost := make([][]float64, full+1)
+	parent := make([][]int, full+1)
+	for mask := range cost {
+		cost[mask] = make([]float64, n)
+		parent[mask] = make([]int, n)
+		for j := range cost[mask] {
+			cost[mask][j] = math.Inf(1)
+			parent[mask][j] = -1
+		}
+	}
+	for j := 0; j < n; j++ {
+		cost[1<<j][j] = dist[0][j+1]
+	}
+	for mask := 1; mask <= full; mask++ {
+		for j := 0; j < n; j++ {
+			if mask&(1<<j) == 0 || math.IsInf(cost[mask][j], 1) {
+				continue
+			}
+			for k := 0; k < n; k++ {
+				if mask&(1<<k) != 0 {
+					continue
+				}
+				next := mask | 1<<k
+				c := cost[mask][j] + dist[j+1][k+1]
+				if c < cost[next][k] {
+					cost[next][k] = c
+					parent[next][k] = j
+				}
+			}
+		}
+	}
+
+	last, best := 0, math.Inf(1)
+	for j := 0; j < n; j++ {
+		c := cost[full][j] + dist[j+1][end]
+		if c < best {
+			best = c
+			last = j
+		}
+	}
+
+	order := make([]int, n)
+	mask := full
+	for i := n - 1; i >= 0; i-- {
+		order[i] = last
+		prev := parent[mask][last]
+		mask &^= 1 << last
+		last = prev
+	}
+	return order
+}
+
+// nearestNeighborOrder walks greedily from the starting merchant to the closest
+// unvisited merchant.
+func nearestNeighborOrder(startingMerchant *merchants.Merchants, merchantListToVisit []*merchants.Merchants) []int {
+	index := make(map[string]int, len(merchantListToVisit)) // key: merchant id
+	visited := make(map[string]bool)                        // string: merchant id, bool: has visited
+	for i, merchant := range merchantListToVisit {
+		index[merchant.UID] = i
+		visited[merchant.UID] = false
+	}
+	order := make([]int, 0, len(merchantListToVisit))
+	point := haversine.NewCoordinates(startingMerchant.Lat, startingMerchant.Lng)
+	for len(order) < len(merchantListToVisit) {
+		merchant, _ := NearestNeighbor(point, GetPointsToCalculate(merchantListToVisit, visited))
+		visited[merchant.UID] = true
+		order = append(order, index[merchant.UID])
+		point = haversine.NewCoordinates(merchant.Lat, merchant.Lng)
+	}
+	return order
+}
+
+// twoOpt reverses segments of the visit order while that shortens the open path.
+// The starting merchant and the user stay fixed at both ends.
+func twoOpt(dist [][]float64, order []int) []int {
+	end := len(dist) - 1
+	// path holds point indexes: start, merchants..., end
+	path := make([]int, 0, len(order)+2)
+	path = append(path, 0)
+	for _, i := range order {
+		path = append(path, i+1)
+	}
+	path = append(path, end)
+
+	const epsilon = 1e-12
+	improved := true
+	for improved {
+		improved = false
+		for i := 1; i < len(path)-2; i++ {
+			for k := i + 1; k < len(path)-1; k++ {
+				delta := dist[path[i-1]][path[k]] + dist[path[i]][path[k+1]] -
+					dist[path[i-1]][path[i]] - dist[path[k]][path[k+1]]
+				if delta < -epsilon {
+					for l, r := i, k; l < r; l, r = l+1, r-1 {
+						path[l], path[r] = path[r], path[l]
+					}
+					improved = true
+				}
+			}
+		}
+	}
+
+	res := make([]int, 0, len(order))
+	for _, p := range path[1 : len(path)-1] {
+		res = append(res, p-1)
+	}
+	return res
+}
@@ -0,0 +1,221 @@
+package haversine
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/LucaTheHacker/go-haversine"
+	"github.com/citadel-corp/belimang/internal/merchants"
+)
+
+const routeTolerance = 1e-9
+
+// routeCase is a starting merchant, merchants to visit and a user location.
+type routeCase struct {
+	name      string
+	start     [2]float64
+	merchants [][2]float64
+	user      [2]float64
+}
+
+func (c routeCase) merchantList() []*merchants.Merchants {
+	list := []*merchants.Merchants{{UID: "start", Lat: c.start[0], Lng: c.start[1]}}
+	for i, m := range c.merchants {
+		list = append(list, &merchants.Merchants{UID: fmt.Sprintf("merchant%d", i), Lat: m[0], Lng: m[1]})
+	}
+	return list
+}
+
+func (c routeCase) dist() [][]float64 {
+	points := []haversine.Coordinates{haversine.NewCoordinates(c.start[0], c.start[1])}
+	for _, m := range c.merchants {
+		points = append(points, haversine.NewCoordinates(m[0], m[1]))
+	}
+	points = append(points, haversine.NewCoordinates(c.user[0], c.user[1]))
+	return distanceMatrix(points)
+}
+
+// randomRouteCase places n merchants and the user within about 10 km of Jakarta.
+func randomRouteCase(rng *rand.Rand, n int) routeCase {
+	point := func() [2]float64 {
+		return [2]float64{-6.2 + (rng.Float64()-0.5)*0.2, 106.8 + (rng.Float64()-0.5)*0.2}
+	}
+	c := routeCase{name: fmt.Sprintf("random %d merchants", n), start: point(), user: point()}
+	for range n {
+		c.merchants = append(c.merchants, point())
+	}
+	return c
+}
+
+func routeCases() []routeCase {
+	cases := []routeCase{
+		{name: "no merchant to visit", start: [2]float64{-6.2, 106.8}, user: [2]float64{-6.21, 106.81}},
+		{name: "one merchant", start: [2]float64{-6.2, 106.8}, merchants: [][2]float64{{-6.25, 106.85}}, user: [2]float64{-6.21, 106.81}},
+		{
+			name:      "two merchants",
+			start:     [2]float64{-6.2, 106.8},
+			merchants: [][2]float64{{-6.3, 106.9}, {-6.22, 106.82}},
+			user:      [2]float64{-6.35, 106.95},
+		},
+		{
+			name:      "duplicate coordinates",
+			start:     [2]float64{-6.2, 106.8},
+			merchants: [][2]float64{{-6.25, 106.85}, {-6.25, 106.85}, {-6.2, 106.8}, {-6.3, 106.7}, {-6.25, 106.85}},
+			user:      [2]float64{-6.25, 106.85},
+		},
+		{
+			// nearest neighbor walks east first and has to come all the way back
+			name:      "greedy trap",
+			start:     [2]float64{0, 0},
+			merchants: [][2]float64{{0, 0.01}, {0, -0.011}, {0, -0.05}, {0, 0.06}},
+			user:      [2]float64{0, 0.07},
+		},
+	}
+	rng := rand.New(rand.NewSource(42))
+	for n := 3; n <= 8; n++ {
+		for range 5 {
+			cases = append(cases, randomRouteCase(rng, n))
+		}
+	}
+	return cases
+}
+
+// pathCost returns the length of the open path from the start through order to
+// the user.
+func pathCost(dist [][]float64, order []int) float64 {
+	cost, prev := 0.0, 0
+	for _, i := range order {
+		cost += dist[prev][i+1]
+		prev = i + 1
+	}
+	return cost + dist[prev][len(dist)-1]
+}
+
+// bruteForceCost returns the length of the shortest open path, trying every
+// visit order.
+func bruteForceCost(dist [][]float64) float64 {
+	n := len(dist) - 2
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	best := math.Inf(1)
+	var permute func(k int)
+	permute = func(k int) {
+		if k == n {
+			best = math.Min(best, pathCost(dist, order))
+			return
+		}
+		for i := k; i < n; i++ {
+			order[k], order[i] = order[i], order[k]
+			permute(k + 1)
+			order[k], order[i] = order[i], order[k]
+		}
+	}
+	permute(0)
+	return best
+}
+
+// assertPermutation fails unless order visits 0..n-1 exactly once.
+func assertPermutation(t *testing.T, order []int, n int) {
+	t.Helper()
+	if len(order) != n {
+		t.Fatalf("order %v has %d stops, want %d", order, len(order), n)
+	}
+	seen := make([]bool, n)
+	for _, i := range order {
+		if i < 0 || i >= n || seen[i] {
+			t.Fatalf("order %v is not a permutation of 0..%d", order, n-1)
+		}
+		seen[i] = true
+	}
+}
+
+func TestHeldKarpIsOptimal(t *testing.T) {
+	for _, tt := range routeCases() {
+		t.Run(tt.name, func(t *testing.T) {
+			dist := tt.dist()
+			order := heldKarp(dist)
+			assertPermutation(t, order, len(tt.merchants))
+			got, want := pathCost(dist, order), bruteForceCost(dist)
+			if math.Abs(got-want) > routeTolerance {
+				t.Errorf("heldKarp() cost = %v, brute force = %v", got, want)
+			}
+		})
+	}
+}
+
+func TestTwoOptNeverWorseThanNearestNeighbor(t *testing.T) {
+	for _, tt := range routeCases() {
+		t.Run(tt.name, func(t *testing.T) {
+			dist := tt.dist()
+			list := tt.merchantList()
+			nearest := nearestNeighborOrder(list[0], list[1:])
+			assertPermutation(t, nearest, len(tt.merchants))
+			improved := twoOpt(dist, nearest)
+			assertPermutation(t, improved, len(tt.merchants))
+			if got, greedy := pathCost(dist, improved), pathCost(dist, nearest); got > greedy+routeTolerance {
+				t.Errorf("twoOpt() cost = %v, nearest neighbor = %v", got, greedy)
+			}
+			if got, best := pathCost(dist, improved), bruteForceCost(dist); got < best-routeTolerance {
+				t.Errorf("twoOpt() cost = %v, below the optimum %v", got, best)
+			}
+		})
+	}
+}
+
+func TestPlanRoute(t *testing.T) {
+	for _, tt := range routeCases() {
+		t.Run(tt.name, func(t *testing.T) {
+			route := PlanRoute(tt.user[0], tt.user[1], "start", tt.merchantList())
+			if len(route.Stops) != len(tt.merchants)+1 || len(route.Legs) != len(tt.merchants)+1 {
+				t.Fatalf("PlanRoute() has %d stops and %d legs, want %d of each", len(route.Stops), len(route.Legs), len(tt.merchants)+1)
+			}
+			if route.Stops[0].UID != "start" {
+				t.Errorf("PlanRoute() starts at %s, want start", route.Stops[0].UID)
+			}
+			sum := 0.0
+			for _, leg := range route.Legs {
+				sum += leg
+			}
+			if math.Abs(sum-route.TotalDistance) > routeTolerance {
+				t.Errorf("PlanRoute() legs sum to %v, total distance = %v", sum, route.TotalDistance)
+			}
+			if want := bruteForceCost(tt.dist()); math.Abs(route.TotalDistance-want) > routeTolerance {
+				t.Errorf("PlanRoute() total distance = %v, brute force = %v", route.TotalDistance, want)
+			}
+		})
+	}
+}
+
+func TestPlanRouteFallsBackPastMaxExactRouteMerchants(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	tt := randomRouteCase(rng, MaxExactRouteMerchants+1)
+	list := tt.merchantList()
+	dist := tt.dist()
+
+	route := PlanRoute(tt.user[0], tt.user[1], "start", list)
+	if len(route.Stops) != len(list) {
+		t.Fatalf("PlanRoute() has %d stops, want %d", len(route.Stops), len(list))
+	}
+	seen := make(map[string]bool)
+	for _, stop := range route.Stops {
+		if seen[stop.UID] {
+			t.Fatalf("PlanRoute() visits %s twice", stop.UID)
+		}
+		seen[stop.UID] = true
+	}
+	greedy := pathCost(dist, nearestNeighborOrder(list[0], list[1:]))
+	if route.TotalDistance > greedy+routeTolerance {
+		t.Errorf("PlanRoute() total distance = %v, nearest neighbor = %v", route.TotalDistance, greedy)
+	}
+}
+
+func TestPlanRouteUnknownStartingMerchant(t *testing.T) {
+	route := PlanRoute(-6.2, 106.8, "missing", routeCase{merchants: [][2]float64{{-6.25, 106.85}}}.merchantList())
+	if len(route.Stops) != 0 || route.TotalDistance != 0 {
+		t.Errorf("PlanRoute() = %+v, want an empty route", route)
+	}
+}
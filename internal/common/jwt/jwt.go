@@ -2,59 +2,67 @@ package jwt
 
 import (
 	"errors"
-	"fmt"
 	"os"
 	"time"
 
+	"github.com/citadel-corp/belimang/internal/common/id"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const (
+	DefaultIssuer   = "belimang"
+	DefaultAudience = "belimang"
+)
+
 var (
-	key = []byte(os.Getenv("JWT_SECRET"))
+	// keyring signs and verifies with the HS256 JWT_SECRET until Use replaces it.
+	keyring = NewHMACKeyring([]byte(os.Getenv("JWT_SECRET")), DefaultIssuer, DefaultAudience)
 
 	ErrUnknownClaims = errors.New("unknown claims type")
 	ErrTokenInvalid  = errors.New("invalid token")
 )
 
 type UserClaims struct {
-	UserUID string `json:"uid"`
-	Role    string `json:"role"`
+	UserUID     string   `json:"uid"`
+	Role        string   `json:"role"`
+	SessionID   string   `json:"sid,omitempty"` // the refresh token family the token was issued in
+	Permissions []string `json:"perms,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func Sign(ttl time.Duration, subject, role string) (string, error) {
+// Use makes Sign and VerifyAndGetSubject use ring. It is meant to be called once
+// at startup.
+func Use(ring *Keyring) {
+	keyring = ring
+}
+
+// PublicKeys returns the public keys verifying the tokens.
+func PublicKeys() JWKS {
+	return keyring.JWKS()
+}
+
+// Sign issues a token for the subject with the role and permissions, valid for
+// ttl, in the session of sessionID. Every token gets its own jti.
+func Sign(ttl time.Duration, subject, role, sessionID string, permissions []string) (string, error) {
 	now := time.Now()
 	expiry := now.Add(ttl)
 
 	claims := UserClaims{
-		UserUID: subject,
-		Role:    role,
+		UserUID:     subject,
+		Role:        role,
+		SessionID:   sessionID,
+		Permissions: permissions,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id.GenerateStringID(16),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(expiry),
 		},
 	}
 
-	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return t.SignedString(key)
+	return keyring.sign(claims)
 }
 
 func VerifyAndGetSubject(tokenString string) (*UserClaims, error) {
-	// Parse the token
-	token, err := jwt.ParseWithClaims(tokenString, &UserClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return key, nil
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	if claims, ok := token.Claims.(*UserClaims); ok && token.Valid {
-		return claims, nil
-	} else {
-		return nil, ErrUnknownClaims
-	}
+	return keyring.verify(tokenString)
 }
@@ -0,0 +1,242 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/citadel-corp/belimang/internal/common/env"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrKeyNotFound = errors.New("signing key not found")
+
+// Key is a key of the keyring identified by its kid. Keys without a private
+// half, e.g. retired ones, only verify.
+type Key struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.PrivateKey
+	PublicKey  crypto.PublicKey
+}
+
+// Keyring signs with one key and verifies with any of its keys, so keys can be
+// rotated without invalidating the tokens signed with the previous one.
+type Keyring struct {
+	keys      map[string]Key
+	signingID string
+	secret    []byte // HS256 key of the tokens without a kid
+	issuer    string
+	audience  string
+}
+
+// NewHMACKeyring returns a keyring signing with HS256 and secret, like the
+// tokens issued before key rotation.
+func NewHMACKeyring(secret []byte, issuer, audience string) *Keyring {
+	return &Keyring{keys: make(map[string]Key), secret: secret, issuer: issuer, audience: audience}
+}
+
+// LoadKeyring loads the PEM keys of the JWT_KEYS_DIR directory, the file name
+// without extension being the kid. JWT_SIGNING_KEY_ID chooses the signing key,
+// and defaults to the greatest kid with a private key so that naming keys by
+// date rotates them. Without JWT_KEYS_DIR, tokens are signed with HS256 and
+// JWT_SECRET, which also keeps verifying tokens without a kid when set.
+func LoadKeyring() (*Keyring, error) {
+	issuer, audience := env.String("JWT_ISSUER", DefaultIssuer), env.String("JWT_AUDIENCE", DefaultAudience)
+	ring := NewHMACKeyring([]byte(env.String("JWT_SECRET", "")), issuer, audience)
+
+	dir := env.String("JWT_KEYS_DIR", "")
+	if dir == "" {
+		if len(ring.secret) == 0 {
+			return nil, errors.New("JWT_SECRET or JWT_KEYS_DIR must be set")
+		}
+		return ring, nil
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("JWT_KEYS_DIR: %w", err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("JWT_KEYS_DIR: %w", err)
+		}
+		key, err := ParseKey(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), data)
+		if err != nil {
+			return nil, fmt.Errorf("JWT_KEYS_DIR: %s: %w", filepath.Base(path), err)
+		}
+		ring.Add(key)
+	}
+
+	signingID := env.String("JWT_SIGNING_KEY_ID", "")
+	if signingID == "" {
+		for kid, key := range ring.keys {
+			if key.PrivateKey != nil && kid > signingID {
+				signingID = kid
+			}
+		}
+	}
+	if err = ring.SignWith(signingID); err != nil {
+		return nil, fmt.Errorf("JWT_SIGNING_KEY_ID %q: %w", signingID, err)
+	}
+	return ring, nil
+}
+
+// ParseKey parses a PEM encoded RSA or Ed25519 key, private or public.
+func ParseKey(kid string, data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, errors.New("no PEM data")
+	}
+	var (
+		parsed any
+		err    error
+	)
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return Key{}, fmt.Errorf("unsupported PEM type %q", block.Type)
+	}
+	if err != nil {
+		return Key{}, err
+	}
+
+	key := Key{ID: kid}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.PrivateKey, key.PublicKey = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.PublicKey = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.PrivateKey, key.PublicKey = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.PublicKey = jwt.SigningMethodEdDSA, k
+	default:
+		return Key{}, fmt.Errorf("unsupported key type %T", parsed)
+	}
+	return key, nil
+}
+
+// Add adds key to the keys verifying tokens, replacing the key with the same kid.
+func (r *Keyring) Add(key Key) {
+	r.keys[key.ID] = key
+}
+
+// SignWith makes the key kid sign the new tokens.
+func (r *Keyring) SignWith(kid string) error {
+	key, ok := r.keys[kid]
+	if !ok || key.PrivateKey == nil {
+		return ErrKeyNotFound
+	}
+	r.signingID = kid
+	return nil
+}
+
+func (r *Keyring) sign(claims UserClaims) (string, error) {
+	claims.Issuer = r.issuer
+	claims.Audience = jwt.ClaimStrings{r.audience}
+	if r.signingID == "" {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(r.secret)
+	}
+	key := r.keys[r.signingID]
+	t := jwt.NewWithClaims(key.Method, claims)
+	t.Header["kid"] = key.ID
+	return t.SignedString(key.PrivateKey)
+}
+
+func (r *Keyring) verify(tokenString string) (*UserClaims, error) {
+	claims := &UserClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || len(r.secret) == 0 {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return r.secret, nil
+		}
+		key, ok := r.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, kid)
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key.PublicKey, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, ErrTokenInvalid
+	}
+
+	// HS256 tokens issued before issuer and audience existed are let through
+	// until they expire, every other token must carry both
+	_, hasKid := token.Header["kid"]
+	if hasKid || claims.Issuer != "" || len(claims.Audience) > 0 {
+		if claims.Issuer != r.issuer || !slices.Contains(claims.Audience, r.audience) {
+			return nil, ErrTokenInvalid
+		}
+	}
+	return claims, nil
+}
+
+// JWK is a public key in the JSON Web Key format.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`   // RSA modulus
+	E         string `json:"e,omitempty"`   // RSA exponent
+	Curve     string `json:"crv,omitempty"` // OKP curve
+	X         string `json:"x,omitempty"`   // OKP public key
+}
+
+// JWKS is the JSON Web Key Set other services verify our tokens with.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the keyring, sorted by kid. The HS256 secret
+// is never published.
+func (r *Keyring) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(r.keys))}
+	for _, key := range r.keys {
+		jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: key.Method.Alg()}
+		switch k := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(k.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(k)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	slices.SortFunc(set.Keys, func(a, b JWK) int {
+		return strings.Compare(a.KeyID, b.KeyID)
+	})
+	return set
+}
@@ -2,37 +2,72 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"regexp"
 
 	"github.com/citadel-corp/belimang/internal/common/jwt"
+	"github.com/citadel-corp/belimang/internal/common/permission"
+	"github.com/gorilla/mux"
 )
 
 type ContextAuthKey struct{}
 
-func Authorized(next func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
+var routeVarPattern = regexp.MustCompile(`\{\w+\}`)
 
-		tokenString := r.Header.Get("Authorization")
-		if tokenString == "" {
-			w.WriteHeader(http.StatusUnauthorized)
-			return
-		}
+// RevocationList tells whether a token was revoked before it expired.
+type RevocationList interface {
+	IsRevoked(ctx context.Context, claims *jwt.UserClaims) (bool, error)
+}
 
-		if len(tokenString) <= len("Bearer ") {
-			w.WriteHeader(http.StatusUnauthorized)
-			return
-		}
+var revocationList RevocationList
 
-		tokenString = tokenString[len("Bearer "):]
-		if tokenString == "" {
-			w.WriteHeader(http.StatusUnauthorized)
-			return
-		}
+// UseRevocationList makes the authorization middlewares reject the tokens list
+// revoked. Until it is called no token is considered revoked.
+func UseRevocationList(list RevocationList) {
+	revocationList = list
+}
 
-		subject, err := jwt.VerifyAndGetSubject(tokenString)
+func isRevoked(ctx context.Context, claims *jwt.UserClaims) (bool, error) {
+	if revocationList == nil {
+		return false, nil
+	}
+	return revocationList.IsRevoked(ctx, claims)
+}
+
+var errNoToken = errors.New("no bearer token")
+
+// authenticate returns the claims of the bearer token of r, or the status to
+// reject r with. A request without a token fails with errNoToken.
+func authenticate(r *http.Request) (*jwt.UserClaims, int, error) {
+	tokenString := r.Header.Get("Authorization")
+	if len(tokenString) <= len("Bearer ") {
+		return nil, http.StatusUnauthorized, errNoToken
+	}
+	tokenString = tokenString[len("Bearer "):]
+
+	subject, err := jwt.VerifyAndGetSubject(tokenString)
+	if err != nil {
+		return nil, http.StatusUnauthorized, err
+	}
+
+	revoked, err := isRevoked(r.Context(), subject)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if revoked {
+		return nil, http.StatusUnauthorized, errors.New("token revoked")
+	}
+	return subject, 0, nil
+}
+
+func Authorized(next func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		subject, status, err := authenticate(r)
 		if err != nil {
-			w.WriteHeader(http.StatusUnauthorized)
+			w.WriteHeader(status)
 			return
 		}
 
@@ -48,26 +83,13 @@ func Authenticate(next func(w http.ResponseWriter, r *http.Request)) func(w http
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
-		tokenString := r.Header.Get("Authorization")
-		if tokenString == "" {
+		subject, status, err := authenticate(r)
+		if errors.Is(err, errNoToken) {
 			next(w, r)
 			return
 		}
-
-		if len(tokenString) <= len("Bearer ") {
-			next(w, r)
-			return
-		}
-
-		tokenString = tokenString[len("Bearer "):]
-		if tokenString == "" {
-			next(w, r)
-			return
-		}
-
-		subject, err := jwt.VerifyAndGetSubject(tokenString)
 		if err != nil {
-			w.WriteHeader(http.StatusUnauthorized)
+			w.WriteHeader(status)
 			return
 		}
 
@@ -82,31 +104,43 @@ func AuthorizeRole(next func(w http.ResponseWriter, r *http.Request), role strin
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
-		tokenString := r.Header.Get("Authorization")
-		if tokenString == "" {
-			w.WriteHeader(http.StatusUnauthorized)
+		subject, status, err := authenticate(r)
+		if err != nil {
+			w.WriteHeader(status)
 			return
 		}
 
-		if len(tokenString) <= len("Bearer ") {
+		if subject.Role != role {
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
 
-		tokenString = tokenString[len("Bearer "):]
-		if tokenString == "" {
-			w.WriteHeader(http.StatusUnauthorized)
-			return
-		}
+		ctx := context.WithValue(r.Context(), ContextAuthKey{}, subject)
+		r = r.WithContext(ctx)
+
+		next(w, r)
+	}
+}
 
-		subject, err := jwt.VerifyAndGetSubject(tokenString)
+// RequirePermission lets the request through only when the token grants
+// permission. Route variables in braces are expanded, so
+// "merchant:write:{merchantId}" requires the permission on the merchant of the URL.
+func RequirePermission(next func(w http.ResponseWriter, r *http.Request), required string) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		subject, status, err := authenticate(r)
 		if err != nil {
-			w.WriteHeader(http.StatusUnauthorized)
+			w.WriteHeader(status)
 			return
 		}
 
-		if subject.Role != role {
-			w.WriteHeader(http.StatusUnauthorized)
+		vars := mux.Vars(r)
+		expanded := routeVarPattern.ReplaceAllStringFunc(required, func(v string) string {
+			return vars[v[1:len(v)-1]]
+		})
+		if !permission.Grants(subject.Permissions, expanded) {
+			w.WriteHeader(http.StatusForbidden)
 			return
 		}
 
@@ -116,3 +150,9 @@ func AuthorizeRole(next func(w http.ResponseWriter, r *http.Request), role strin
 		next(w, r)
 	}
 }
+
+// GetClaims returns the claims of the token the request was authorized with.
+func GetClaims(ctx context.Context) (*jwt.UserClaims, bool) {
+	claims, ok := ctx.Value(ContextAuthKey{}).(*jwt.UserClaims)
+	return claims, ok
+}
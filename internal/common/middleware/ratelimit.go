@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/citadel-corp/belimang/internal/common/ratelimit"
+	"github.com/citadel-corp/belimang/internal/common/request"
+	"github.com/citadel-corp/belimang/internal/common/response"
+	"github.com/rs/zerolog/log"
+)
+
+var rateLimitStore ratelimit.Store
+
+// UseRateLimitStore makes RateLimit keep its token buckets in store. Until it is
+// called no request is limited.
+func UseRateLimitStore(store ratelimit.Store) {
+	rateLimitStore = store
+}
+
+// RateLimit limits the requests of every client to next as policy says. Clients
+// are told apart by the UID of their token when the request was authorized
+// before, so wrap it in an authorization middleware, and by their IP address
+// otherwise, which only trusted proxies may forward. The limit is advertised in
+// the RateLimit-* headers.
+func RateLimit(next func(w http.ResponseWriter, r *http.Request), policy ratelimit.Policy) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if rateLimitStore == nil {
+			next(w, r)
+			return
+		}
+
+		key := policy.Name + ":ip:" + request.ClientIP(r)
+		if claims, ok := GetClaims(r.Context()); ok {
+			key = policy.Name + ":user:" + claims.UserUID
+		}
+		res, err := rateLimitStore.Take(r.Context(), key, policy)
+		if err != nil {
+			// an unavailable store lets requests through rather than failing them all
+			log.Error().Msgf("rate limit %s: %v", policy.Name, err)
+			next(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("RateLimit-Policy", strconv.Itoa(policy.Limit)+";w="+strconv.Itoa(seconds(policy.Period)))
+		h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(seconds(res.Reset)))
+		if !res.Allowed {
+			h.Set("Retry-After", strconv.Itoa(seconds(res.RetryAfter)))
+			response.JSON(w, http.StatusTooManyRequests, response.ResponseBody{
+				Message: "Too many requests",
+				Error:   "rate limit exceeded",
+			})
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// seconds rounds d up to whole seconds, as the headers want them.
+func seconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/citadel-corp/belimang/internal/common/ratelimit"
+	"github.com/citadel-corp/belimang/internal/common/request"
+)
+
+// recordingStore records the bucket keys it is asked to take tokens from.
+type recordingStore struct {
+	ratelimit.Store
+	keys []string
+}
+
+func (s *recordingStore) Take(ctx context.Context, key string, policy ratelimit.Policy) (ratelimit.Result, error) {
+	s.keys = append(s.keys, key)
+	return s.Store.Take(ctx, key, policy)
+}
+
+func TestRateLimitIgnoresForgedForwardedFor(t *testing.T) {
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/8")
+	request.UseTrustedProxies([]*net.IPNet{proxies})
+	defer request.UseTrustedProxies(nil)
+	store := &recordingStore{Store: ratelimit.NewMemoryStore()}
+	UseRateLimitStore(store)
+	defer UseRateLimitStore(nil)
+
+	policy := ratelimit.Policy{Name: "login", Limit: 1, Period: time.Minute}
+	handler := RateLimit(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}, policy)
+
+	statuses := make([]int, 0)
+	for _, forwarded := range []string{"198.51.100.1", "198.51.100.2", "198.51.100.3"} {
+		r := httptest.NewRequest(http.MethodPost, "/users/login", nil)
+		r.RemoteAddr = "192.168.1.20:1234"
+		r.Header.Set("X-Forwarded-For", forwarded)
+		w := httptest.NewRecorder()
+		handler(w, r)
+		statuses = append(statuses, w.Code)
+	}
+
+	for _, key := range store.keys {
+		if key != "login:ip:192.168.1.20" {
+			t.Errorf("bucket key = %s, want login:ip:192.168.1.20", key)
+		}
+	}
+	if statuses[0] != http.StatusOK || statuses[1] != http.StatusTooManyRequests || statuses[2] != http.StatusTooManyRequests {
+		t.Errorf("statuses = %v, want the requests after the first limited", statuses)
+	}
+}
@@ -4,12 +4,16 @@ import (
 	"errors"
 	"os"
 	"strconv"
+	"sync"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
 var (
 	costStr = os.Getenv("BCRYPT_SALT")
+
+	dummyOnce sync.Once
+	dummyHash []byte
 )
 
 func Hash(plaintextPassword string) (string, error) {
@@ -38,3 +42,18 @@ func Matches(plaintextPassword, hashedPassword string) (bool, error) {
 
 	return true, nil
 }
+
+// Mismatch compares plaintextPassword with a hash of the configured cost and
+// discards the result, taking as long as Matches does. Checking the password
+// given for an unknown user with it keeps unknown users from being told apart by
+// how fast they are rejected.
+func Mismatch(plaintextPassword string) {
+	dummyOnce.Do(func() {
+		cost, err := strconv.Atoi(costStr)
+		if err != nil {
+			cost = bcrypt.DefaultCost
+		}
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("belimang dummy password"), cost)
+	})
+	_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(plaintextPassword))
+}
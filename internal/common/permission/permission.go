@@ -0,0 +1,74 @@
+// Package permission checks permissions written resource:action:scope, e.g.
+// merchant:write:{merchantId} or orders:read:any.
+package permission
+
+import (
+	"slices"
+	"strings"
+)
+
+const (
+	Merchant   = "merchant"
+	Orders     = "orders"
+	Promotions = "promotions"
+	Users      = "users"
+)
+
+const (
+	Read   = "read"
+	Write  = "write"
+	Status = "status" // move orders through their statuses
+)
+
+// Any is the scope of a permission over every instance of its resource.
+const Any = "any"
+
+var (
+	Resources = []string{Merchant, Orders, Promotions, Users}
+	Actions   = []string{Read, Write, Status}
+)
+
+// New returns the permission to act on the resource in scope.
+func New(resource, action, scope string) string {
+	return resource + ":" + action + ":" + scope
+}
+
+// Parse splits a permission into its parts, ok being false when it is malformed.
+func Parse(permission string) (resource, action, scope string, ok bool) {
+	parts := strings.Split(permission, ":")
+	if len(parts) != 3 || parts[2] == "" {
+		return "", "", "", false
+	}
+	if !slices.Contains(Resources, parts[0]) || !slices.Contains(Actions, parts[1]) {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], parts[2], true
+}
+
+// IsValid reports whether permission is well formed.
+func IsValid(permission string) bool {
+	_, _, _, ok := Parse(permission)
+	return ok
+}
+
+// Grants reports whether the granted permissions allow required. A permission
+// with the Any scope covers every scope of its resource, and write covers read.
+func Grants(granted []string, required string) bool {
+	resource, action, scope, ok := Parse(required)
+	if !ok {
+		return false
+	}
+	for _, g := range granted {
+		gResource, gAction, gScope, ok := Parse(g)
+		if !ok || gResource != resource {
+			continue
+		}
+		if gAction != action && !(gAction == Write && action == Read) {
+			continue
+		}
+		if gScope == Any || gScope == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often full buckets are dropped from memory, a full bucket
+// being the same as none.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time // when it is full again
+}
+
+// MemoryStore keeps the buckets in memory. It is safe for concurrent use.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]bucket), lastSweep: time.Now(), now: time.Now}
+}
+
+// Take implements Store.
+func (s *MemoryStore) Take(ctx context.Context, key string, policy Policy) (Result, error) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.sweep(now)
+	}
+
+	tokens := float64(policy.Limit)
+	if b, ok := s.buckets[key]; ok {
+		tokens = policy.refill(b.tokens, now.Sub(b.updated))
+	}
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+	s.buckets[key] = bucket{
+		tokens:  tokens,
+		updated: now,
+		full:    now.Add(policy.timeToGain(float64(policy.Limit) - tokens)),
+	}
+	return policy.result(tokens, allowed), nil
+}
+
+// Len returns the number of buckets in memory.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets)
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if !b.full.After(now) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
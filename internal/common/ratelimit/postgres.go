@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/citadel-corp/belimang/internal/common/db"
+)
+
+// refilledTokens is the tokens of the bucket b, refilled up to $2 at $3 tokens
+// per second since it was last updated.
+const refilledTokens = `LEAST($2::float8, b.tokens + EXTRACT(EPOCH FROM current_timestamp - b.updated_at)::float8 * $3::float8)`
+
+// PostgresStore keeps the buckets in the rate_limit_buckets table, shared by
+// every instance. A token is taken in a single statement, so concurrent requests
+// never take the same one.
+type PostgresStore struct {
+	db *db.DB
+}
+
+func NewPostgresStore(db *db.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Take implements Store.
+// An empty bucket is left untouched, and then read to tell how long until it
+// has a token.
+func (s *PostgresStore) Take(ctx context.Context, key string, policy Policy) (Result, error) {
+	q := `
+		INSERT INTO rate_limit_buckets AS b (key, tokens, updated_at, full_at)
+		VALUES ($1, $2::float8 - 1, current_timestamp, current_timestamp + make_interval(secs => 1 / $3::float8))
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = ` + refilledTokens + ` - 1,
+			updated_at = current_timestamp,
+			full_at = current_timestamp + make_interval(secs => ($2::float8 - ` + refilledTokens + ` + 1) / $3::float8)
+		WHERE ` + refilledTokens + ` >= 1
+		RETURNING tokens;
+	`
+	var tokens float64
+	err := s.db.Executor(nil).QueryRowContext(ctx, q, key, float64(policy.Limit), policy.perSecond()).Scan(&tokens)
+	if err == nil {
+		return policy.result(tokens, true), nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return Result{}, err
+	}
+
+	q = `SELECT ` + refilledTokens + ` FROM rate_limit_buckets b WHERE key = $1;`
+	err = s.db.Executor(nil).QueryRowContext(ctx, q, key, float64(policy.Limit), policy.perSecond()).Scan(&tokens)
+	if err != nil {
+		return Result{}, err
+	}
+	return policy.result(tokens, false), nil
+}
+
+// Sweep deletes the full buckets, a full bucket being the same as none.
+func (s *PostgresStore) Sweep(ctx context.Context) error {
+	_, err := s.db.Executor(nil).ExecContext(ctx, "DELETE FROM rate_limit_buckets WHERE full_at <= current_timestamp;")
+	return err
+}
@@ -0,0 +1,95 @@
+// Package ratelimit limits requests with token buckets: a bucket holds up to
+// Limit tokens, refills at Limit tokens per Period, and every request takes one.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/citadel-corp/belimang/internal/common/env"
+)
+
+const (
+	MemoryStoreName   = "memory"
+	PostgresStoreName = "postgres"
+)
+
+// Policy is how many requests a client may make: bursts of up to Limit requests,
+// and Limit requests per Period sustained. Name keeps the buckets of different
+// policies apart.
+type Policy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+// Result is the state of a bucket after taking a token from it.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until a token is available, zero when allowed
+}
+
+// Store keeps the token buckets.
+type Store interface {
+	// Take takes a token from the bucket of key, refilled as policy says.
+	Take(ctx context.Context, key string, policy Policy) (Result, error)
+}
+
+// Config picks the store keeping the buckets. Buckets kept in memory are per
+// instance, so deployments of several instances keep them in Postgres.
+type Config struct {
+	Enabled bool
+	Store   string
+}
+
+func LoadConfig() (Config, error) {
+	var (
+		cfg = Config{Enabled: true, Store: MemoryStoreName}
+		err error
+	)
+	if cfg.Enabled, err = env.Bool("RATE_LIMIT", cfg.Enabled); err != nil {
+		return cfg, err
+	}
+	cfg.Store = env.String("RATE_LIMIT_STORE", cfg.Store)
+	if cfg.Store != MemoryStoreName && cfg.Store != PostgresStoreName {
+		return cfg, fmt.Errorf("RATE_LIMIT_STORE must be %s or %s", MemoryStoreName, PostgresStoreName)
+	}
+	return cfg, nil
+}
+
+// perSecond returns the tokens the bucket gains per second.
+func (p Policy) perSecond() float64 {
+	return float64(p.Limit) / p.Period.Seconds()
+}
+
+// refill returns the tokens of a bucket elapsed after it had tokens.
+func (p Policy) refill(tokens float64, elapsed time.Duration) float64 {
+	return min(float64(p.Limit), tokens+elapsed.Seconds()*p.perSecond())
+}
+
+// timeToGain returns how long the bucket takes to gain tokens.
+func (p Policy) timeToGain(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / p.perSecond() * float64(time.Second))
+}
+
+// result returns the result of taking a token, tokens being left in the bucket
+// afterwards.
+func (p Policy) result(tokens float64, allowed bool) Result {
+	r := Result{
+		Allowed:   allowed,
+		Limit:     p.Limit,
+		Remaining: int(tokens),
+		Reset:     p.timeToGain(float64(p.Limit) - tokens),
+	}
+	if !allowed {
+		r.RetryAfter = p.timeToGain(1 - tokens)
+	}
+	return r
+}
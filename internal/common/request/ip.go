@@ -0,0 +1,86 @@
+package request
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/citadel-corp/belimang/internal/common/env"
+)
+
+var trustedProxies []*net.IPNet
+
+// LoadTrustedProxies reads TRUSTED_PROXIES, a comma separated list of the CIDRs
+// or addresses of the proxies in front of the server.
+func LoadTrustedProxies() ([]*net.IPNet, error) {
+	proxies := make([]*net.IPNet, 0)
+	for _, v := range strings.Split(env.String("TRUSTED_PROXIES", ""), ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if !strings.Contains(v, "/") {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return nil, fmt.Errorf("TRUSTED_PROXIES: invalid address %q", v)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, cidr, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+		}
+		proxies = append(proxies, cidr)
+	}
+	return proxies, nil
+}
+
+// UseTrustedProxies makes ClientIP believe the X-Forwarded-For hops added by
+// proxies. Until it is called no proxy is trusted.
+func UseTrustedProxies(proxies []*net.IPNet) {
+	trustedProxies = proxies
+}
+
+// ClientIP returns the IP address of the client of r. A trusted proxy appends
+// the address it got the request from to X-Forwarded-For, so walking it from the
+// right through trusted proxies, the first other address is the client's.
+// Clients can forge the addresses before it, but not that one. Without trusted
+// proxies the header is ignored.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isTrustedProxy(ip) {
+		return host
+	}
+
+	forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !isTrustedProxy(hop) {
+			break
+		}
+	}
+	return ip.String()
+}
+
+func isTrustedProxy(ip net.IP) bool {
+	for _, proxy := range trustedProxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
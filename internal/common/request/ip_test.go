@@ -0,0 +1,72 @@
+package request
+
+import (
+	"net"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/8")
+	tests := []struct {
+		name       string
+		trusted    []*net.IPNet
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{name: "no proxy", remoteAddr: "203.0.113.7:1234", want: "203.0.113.7"},
+		{name: "forged header from a client", trusted: []*net.IPNet{proxies}, remoteAddr: "203.0.113.7:1234", forwarded: "198.51.100.1", want: "203.0.113.7"},
+		{name: "forged header from a private peer without trusted proxies", remoteAddr: "192.168.1.20:1234", forwarded: "198.51.100.1", want: "192.168.1.20"},
+		{name: "forged header from an untrusted private peer", trusted: []*net.IPNet{proxies}, remoteAddr: "192.168.1.20:1234", forwarded: "198.51.100.1", want: "192.168.1.20"},
+		{name: "trusted proxy", trusted: []*net.IPNet{proxies}, remoteAddr: "10.0.0.2:1234", forwarded: "198.51.100.1", want: "198.51.100.1"},
+		{name: "forged hop before the proxy", trusted: []*net.IPNet{proxies}, remoteAddr: "10.0.0.2:1234", forwarded: "192.0.2.9, 198.51.100.1", want: "198.51.100.1"},
+		{name: "chain of trusted proxies", trusted: []*net.IPNet{proxies}, remoteAddr: "10.0.0.2:1234", forwarded: "192.0.2.9, 198.51.100.1, 10.0.0.3", want: "198.51.100.1"},
+		{name: "untrusted private hop", trusted: []*net.IPNet{proxies}, remoteAddr: "10.0.0.2:1234", forwarded: "198.51.100.1, 192.168.1.20", want: "192.168.1.20"},
+		{name: "malformed hop", trusted: []*net.IPNet{proxies}, remoteAddr: "10.0.0.2:1234", forwarded: "198.51.100.1, nonsense", want: "10.0.0.2"},
+		{name: "trusted proxy without header", trusted: []*net.IPNet{proxies}, remoteAddr: "10.0.0.2:1234", want: "10.0.0.2"},
+	}
+	defer UseTrustedProxies(nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			UseTrustedProxies(tt.trusted)
+			r := httptest.NewRequest("POST", "/users/login", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadTrustedProxies(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 172.16.0.1 ,fd00::/8")
+	proxies, err := LoadTrustedProxies()
+	if err != nil {
+		t.Fatalf("LoadTrustedProxies() error = %v", err)
+	}
+	for _, ip := range []string{"10.1.2.3", "172.16.0.1", "fd00::1"} {
+		found := false
+		for _, proxy := range proxies {
+			found = found || proxy.Contains(net.ParseIP(ip))
+		}
+		if !found {
+			t.Errorf("%s is not trusted", ip)
+		}
+	}
+	for _, ip := range []string{"172.16.0.2", "192.168.1.1"} {
+		for _, proxy := range proxies {
+			if proxy.Contains(net.ParseIP(ip)) {
+				t.Errorf("%s is trusted by %s", ip, proxy)
+			}
+		}
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/33")
+	if _, err := LoadTrustedProxies(); err == nil {
+		t.Error("LoadTrustedProxies() accepted an invalid CIDR")
+	}
+}
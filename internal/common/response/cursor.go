@@ -0,0 +1,60 @@
+package response
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks the last row of a page in keyset pagination. Listings ordered by
+// creation time set CreatedAt, listings ordered by distance set Distance, and ID
+// breaks ties between rows sharing them.
+type Cursor struct {
+	CreatedAt *time.Time `json:"c,omitempty"`
+	Distance  *float64   `json:"d,omitempty"`
+	ID        string     `json:"i"`
+}
+
+// Encode returns the cursor as an opaque token for the client to send back.
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor decodes a token made by Encode. An empty token decodes to nil,
+// which starts from the first page.
+func DecodeCursor(token string) (*Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	c := &Cursor{}
+	if err = json.Unmarshal(b, c); err != nil || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// DecodeCreatedAtCursor decodes the cursor of a listing ordered by creation time.
+func DecodeCreatedAtCursor(token string) (*Cursor, error) {
+	c, err := DecodeCursor(token)
+	if err == nil && c != nil && c.CreatedAt == nil {
+		return nil, ErrInvalidCursor
+	}
+	return c, err
+}
+
+// DecodeDistanceCursor decodes the cursor of a listing ordered by distance.
+func DecodeDistanceCursor(token string) (*Cursor, error) {
+	c, err := DecodeCursor(token)
+	if err == nil && c != nil && c.Distance == nil {
+		return nil, ErrInvalidCursor
+	}
+	return c, err
+}
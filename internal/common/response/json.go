@@ -12,10 +12,15 @@ type ResponseBody struct {
 	Meta    *Pagination `json:"meta,omitempty"`
 }
 
+// Pagination describes a page of a listing. Listings are paged by offset unless
+// the request sends a cursor, in which case Offset and Total are left unset and
+// NextCursor continues the listing until it comes back empty.
+// Listings that are costly to count leave Total unset unless asked for it.
 type Pagination struct {
-	Limit  int `json:"limit"`
-	Offset int `json:"offset"`
-	Total  int `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	Total      int    `json:"total"`
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
 func JSON(w http.ResponseWriter, status int, data any) error {
@@ -0,0 +1,60 @@
+package textsearch
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Query is a name search term prepared for full-text and trigram matching.
+type Query struct {
+	TSQuery string // every word as a prefix, e.g. "nasi:* & gor:*"
+	Text    string // lower cased term for trigram similarity
+	Pattern string // LIKE pattern matching the term anywhere in the name
+}
+
+// Parse prepares term for searching. Punctuation only separates words, so the
+// resulting tsquery is always well formed.
+func Parse(term string) Query {
+	words := strings.FieldsFunc(strings.ToLower(term), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	prefixes := make([]string, len(words))
+	for i, word := range words {
+		prefixes[i] = word + ":*"
+	}
+	text := strings.Join(words, " ")
+	return Query{
+		TSQuery: strings.Join(prefixes, " & "),
+		Text:    text,
+		Pattern: "%" + text + "%",
+	}
+}
+
+// IsEmpty reports whether the term has no word to search for.
+func (q Query) IsEmpty() bool {
+	return q.Text == ""
+}
+
+// Condition matches a name by word prefixes, substring or, to tolerate typos,
+// trigram word similarity. vector is the tsvector column indexing name. The
+// condition has ? placeholders for ConditionArgs.
+func Condition(name, vector string) string {
+	return fmt.Sprintf("%s @@ to_tsquery('simple', ?) OR LOWER(%s) LIKE ? OR ? <%% LOWER(%s)", vector, name, name)
+}
+
+// ConditionArgs returns the arguments of Condition.
+func (q Query) ConditionArgs() []interface{} {
+	return []interface{}{q.TSQuery, q.Pattern, q.Text}
+}
+
+// Rank scores how well a name matches, the higher the better. The expression has
+// ? placeholders for RankArgs.
+func Rank(name, vector string) string {
+	return fmt.Sprintf("ts_rank(%s, to_tsquery('simple', ?)) + word_similarity(?, LOWER(%s))", vector, name)
+}
+
+// RankArgs returns the arguments of Rank.
+func (q Query) RankArgs() []interface{} {
+	return []interface{}{q.TSQuery, q.Text}
+}
@@ -0,0 +1,11 @@
+package merchantitems
+
+import "errors"
+
+var (
+	ErrItemNotFound     = errors.New("item not found")
+	ErrValidationFailed = errors.New("validation failed")
+
+	ErrOptionGroupNotFound    = errors.New("option group not found")
+	ErrInvalidOptionSelection = errors.New("invalid option selection")
+)
@@ -1,6 +1,7 @@
 package merchantitems
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/citadel-corp/belimang/internal/common/request"
@@ -49,6 +50,13 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	if errors.Is(err, merchants.ErrForbidden) {
+		response.JSON(w, http.StatusForbidden, response.ResponseBody{
+			Message: "Forbidden",
+			Error:   err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
 			Message: "Internal server error",
@@ -82,6 +90,13 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	}
 
 	itemResp, pagination, err := h.service.List(r.Context(), req)
+	if errors.Is(err, response.ErrInvalidCursor) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
 	if err == merchants.ErrMerchantNotFound {
 		response.JSON(w, http.StatusNotFound, response.ResponseBody{
 			Message: "Merchant not found",
@@ -89,6 +104,13 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	if errors.Is(err, merchants.ErrForbidden) {
+		response.JSON(w, http.StatusForbidden, response.ResponseBody{
+			Message: "Forbidden",
+			Error:   err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
 			Message: "Internal server error",
@@ -102,3 +124,182 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 		Meta:    pagination,
 	})
 }
+
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	var req UpdateMerchantItemPayload
+
+	err := request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	itemResp, err := h.service.Update(r.Context(), mux.Vars(r)["merchantId"], mux.Vars(r)["itemId"], req)
+	if errors.Is(err, ErrValidationFailed) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, merchants.ErrMerchantNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Merchant not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, merchants.ErrForbidden) {
+		response.JSON(w, http.StatusForbidden, response.ResponseBody{
+			Message: "Forbidden",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrItemNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Item not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, itemResp)
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	err := h.service.Delete(r.Context(), mux.Vars(r)["merchantId"], mux.Vars(r)["itemId"])
+	if errors.Is(err, merchants.ErrMerchantNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Merchant not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, merchants.ErrForbidden) {
+		response.JSON(w, http.StatusForbidden, response.ResponseBody{
+			Message: "Forbidden",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrItemNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Item not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "Merchant item deleted successfully",
+	})
+}
+
+func (h *Handler) CreateOptionGroup(w http.ResponseWriter, r *http.Request) {
+	var req CreateOptionGroupPayload
+
+	err := request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	groupResp, err := h.service.CreateOptionGroup(r.Context(), mux.Vars(r)["merchantId"], mux.Vars(r)["itemId"], req)
+	if errors.Is(err, ErrValidationFailed) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, merchants.ErrMerchantNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Merchant not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, merchants.ErrForbidden) {
+		response.JSON(w, http.StatusForbidden, response.ResponseBody{
+			Message: "Forbidden",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrItemNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Item not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusCreated, groupResp)
+}
+
+func (h *Handler) DeleteOptionGroup(w http.ResponseWriter, r *http.Request) {
+	err := h.service.DeleteOptionGroup(r.Context(), mux.Vars(r)["merchantId"], mux.Vars(r)["itemId"], mux.Vars(r)["optionGroupId"])
+	if errors.Is(err, merchants.ErrMerchantNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Merchant not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, merchants.ErrForbidden) {
+		response.JSON(w, http.StatusForbidden, response.ResponseBody{
+			Message: "Forbidden",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrItemNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Item not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrOptionGroupNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Option group not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "Option group deleted successfully",
+	})
+}
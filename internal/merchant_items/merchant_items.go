@@ -5,6 +5,9 @@ import "time"
 var (
 	MinName = 2
 	MaxName = 30
+
+	MinOptionName = 1
+	MaxOptionName = 30
 )
 
 type ItemCategory string
@@ -28,5 +31,6 @@ type MerchantItems struct {
 	Category    ItemCategory
 	Price       int
 	ImageURL    string
+	IsAvailable bool
 	CreatedAt   time.Time
 }
@@ -0,0 +1,72 @@
+package merchantitems
+
+import (
+	"fmt"
+	"slices"
+)
+
+// OptionGroups is a set of options of an item, e.g. size or toppings, of which
+// between MinSelections and MaxSelections are picked.
+type OptionGroups struct {
+	ID            uint64
+	UID           string
+	ItemID        uint64
+	Name          string
+	MinSelections int
+	MaxSelections int
+	Options       []Options
+}
+
+type Options struct {
+	ID            uint64
+	UID           string
+	OptionGroupID uint64
+	Name          string
+	PriceDelta    int
+}
+
+// SelectedOption is an option picked for an ordered item.
+type SelectedOption struct {
+	Group  *OptionGroups
+	Option *Options
+}
+
+// SelectOptions resolves optionUIDs against the option groups of an item, checking
+// every group selection count. It returns the selected options in group order.
+func SelectOptions(groups []OptionGroups, optionUIDs []string) ([]SelectedOption, error) {
+	selected := make(map[string]bool, len(optionUIDs))
+	for _, uid := range optionUIDs {
+		if selected[uid] {
+			return nil, fmt.Errorf("%w: option %s is selected more than once", ErrInvalidOptionSelection, uid)
+		}
+		selected[uid] = true
+	}
+
+	res := make([]SelectedOption, 0, len(optionUIDs))
+	for i := range groups {
+		group := &groups[i]
+		count := 0
+		for j := range group.Options {
+			option := &group.Options[j]
+			if !selected[option.UID] {
+				continue
+			}
+			delete(selected, option.UID)
+			count += 1
+			res = append(res, SelectedOption{Group: group, Option: option})
+		}
+		if count < group.MinSelections || count > group.MaxSelections {
+			return nil, fmt.Errorf("%w: %s needs between %d and %d options, got %d", ErrInvalidOptionSelection,
+				group.Name, group.MinSelections, group.MaxSelections, count)
+		}
+	}
+	if len(selected) > 0 {
+		unknown := make([]string, 0, len(selected))
+		for uid := range selected {
+			unknown = append(unknown, uid)
+		}
+		slices.Sort(unknown)
+		return nil, fmt.Errorf("%w: options %v do not belong to the item", ErrInvalidOptionSelection, unknown)
+	}
+	return res, nil
+}
@@ -2,28 +2,42 @@ package merchantitems
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
-	"strings"
 
 	"github.com/citadel-corp/belimang/internal/common/db"
 	"github.com/citadel-corp/belimang/internal/common/response"
+	"github.com/citadel-corp/belimang/internal/common/textsearch"
 )
 
 type Repository interface {
+	WithTx(tx *sql.Tx) Repository
 	Create(ctx context.Context, item *MerchantItems) (err error)
 	List(ctx context.Context, filter ListMerchantItemsPayload) (items []MerchantItems, pagination *response.Pagination, err error)
 	ListByUIDs(ctx context.Context, uids []string) ([]*MerchantItems, error)
-	ListByMerchantUIDAndName(ctx context.Context, merchantUIDs []string, name string) ([]*MerchantItems, error)
+	GetByUID(ctx context.Context, merchantID uint64, uid string) (item *MerchantItems, err error)
+	Update(ctx context.Context, item *MerchantItems) (err error)
+	Delete(ctx context.Context, merchantID uint64, uid string) (err error)
+	CreateOptionGroup(ctx context.Context, group *OptionGroups) (err error)
+	DeleteOptionGroup(ctx context.Context, itemID uint64, uid string) (err error)
+	ListOptionGroupsByItemIDs(ctx context.Context, itemIDs []uint64) (groups map[uint64][]OptionGroups, err error)
 }
 
 type dbRepository struct {
 	db *db.DB
+	tx *sql.Tx
 }
 
 func NewRepository(db *db.DB) Repository {
 	return &dbRepository{db: db}
 }
 
+// WithTx implements Repository.
+func (d *dbRepository) WithTx(tx *sql.Tx) Repository {
+	return &dbRepository{db: d.db, tx: tx}
+}
+
 func (d *dbRepository) Create(ctx context.Context, item *MerchantItems) (err error) {
 	createItemQuery := `
 		INSERT INTO merchant_items (
@@ -33,7 +47,7 @@ func (d *dbRepository) Create(ctx context.Context, item *MerchantItems) (err err
         )
     `
 
-	_, err = d.db.DB().ExecContext(ctx, createItemQuery, item.UID, item.MerchantID, item.Name,
+	_, err = d.db.Executor(d.tx).ExecContext(ctx, createItemQuery, item.UID, item.MerchantID, item.Name,
 		item.Category, item.Price, item.ImageURL)
 
 	return
@@ -42,35 +56,28 @@ func (d *dbRepository) Create(ctx context.Context, item *MerchantItems) (err err
 func (d *dbRepository) List(ctx context.Context, filter ListMerchantItemsPayload) (items []MerchantItems, pagination *response.Pagination, err error) {
 	items = make([]MerchantItems, 0)
 
-	q := `
-		SELECT COUNT(*) OVER() AS total_count, mi.uid, mi.name, mi.merchant_id, mi.item_category, mi.price, mi.image_url, mi.created_at
+	totalCount := "COUNT(*) OVER()"
+	if filter.Cursor != nil {
+		// counting every match is what slows down deep pages, so cursor pages skip it
+		totalCount = "0"
+	}
+	q := db.NewQuery(fmt.Sprintf(`
+		SELECT %s AS total_count, mi.id, mi.uid, mi.name, mi.merchant_id, mi.item_category, mi.price, mi.image_url, mi.is_available, mi.created_at
 		FROM merchant_items mi
-	`
-
-	paramNo := 1
-	params := make([]interface{}, 0)
+	`, totalCount))
+	q.Where("mi.deleted_at IS NULL")
 	if filter.MerchantID != 0 {
-		q += "JOIN merchants m ON mi.merchant_id = m.id "
-		q += fmt.Sprintf("WHERE m.id = $%d ", paramNo)
-		paramNo += 1
-		params = append(params, filter.MerchantID)
+		q.Where("mi.merchant_id = ?", filter.MerchantID)
 	}
 	if filter.ItemUID != "" {
-		q += fmt.Sprintf("WHERE mi.uid = $%d ", paramNo)
-		paramNo += 1
-		params = append(params, filter.ItemUID)
+		q.Where("mi.uid = ?", filter.ItemUID)
 	}
-	if filter.Name != "" {
-		q += whereOrAnd(paramNo)
-		q += fmt.Sprintf("LOWER(mi.name) LIKE $%d ", paramNo)
-		paramNo += 1
-		params = append(params, "%"+strings.ToLower(filter.Name)+"%")
+	search := textsearch.Parse(filter.Name)
+	if !search.IsEmpty() {
+		q.Where(textsearch.Condition("mi.name", "mi.search_vector"), search.ConditionArgs()...)
 	}
 	if filter.ProductCategory != "" {
-		q += whereOrAnd(paramNo)
-		q += fmt.Sprintf("mi.item_category = $%d ", paramNo)
-		paramNo += 1
-		params = append(params, filter.ProductCategory)
+		q.Where("mi.item_category = ?", filter.ProductCategory)
 	}
 
 	orderBy := "desc"
@@ -78,53 +85,75 @@ func (d *dbRepository) List(ctx context.Context, filter ListMerchantItemsPayload
 		orderBy = "asc"
 	}
 
-	q += fmt.Sprintf(" ORDER BY mi.created_at %s", orderBy)
-
-	q += fmt.Sprintf(" OFFSET $%d LIMIT $%d", paramNo, paramNo+1)
-	params = append(params, filter.Offset)
-	params = append(params, filter.Limit)
+	if filter.Cursor != nil {
+		var after *response.Cursor
+		after, err = response.DecodeCreatedAtCursor(*filter.Cursor)
+		if err != nil {
+			return
+		}
+		if after != nil {
+			op := "<"
+			if orderBy == "asc" {
+				op = ">"
+			}
+			q.Where("(mi.created_at, mi.uid) "+op+" (?, ?)", *after.CreatedAt, after.ID)
+		}
+		// cursor pages keep to the created at order, a relevance rank being no stable key
+		q.OrderBy("mi.created_at " + orderBy).OrderBy("mi.uid " + orderBy)
+		q.Limit(filter.Limit + 1)
+	} else {
+		// name searches are ordered by relevance unless a created at sort is asked for
+		if !search.IsEmpty() && filter.CreatedAtSort == "" {
+			q.OrderBy(textsearch.Rank("mi.name", "mi.search_vector")+" DESC", search.RankArgs()...)
+		}
+		q.OrderBy("mi.created_at " + orderBy)
+		q.Page(filter.Limit, filter.Offset)
+	}
 
-	rows, err := d.db.DB().QueryContext(ctx, q, params...)
+	query, args := q.Build()
+	rows, err := d.db.Executor(d.tx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return
 	}
+	defer rows.Close()
 
 	pagination = &response.Pagination{}
 	pagination.Limit = filter.Limit
-	pagination.Offset = filter.Offset
+	if filter.Cursor == nil {
+		pagination.Offset = filter.Offset
+	}
 
 	for rows.Next() {
 		m := MerchantItems{}
-		err = rows.Scan(&pagination.Total, &m.UID, &m.Name, &m.MerchantID, &m.Category, &m.Price, &m.ImageURL, &m.CreatedAt)
+		err = rows.Scan(&pagination.Total, &m.ID, &m.UID, &m.Name, &m.MerchantID, &m.Category, &m.Price, &m.ImageURL, &m.IsAvailable, &m.CreatedAt)
 		if err != nil {
 			return
 		}
 		items = append(items, m)
 	}
+	if filter.Cursor != nil && len(items) > filter.Limit {
+		items = items[:filter.Limit]
+		last := items[len(items)-1]
+		pagination.NextCursor = response.Cursor{CreatedAt: &last.CreatedAt, ID: last.UID}.Encode()
+	}
 	return
 }
 
 // ListByUID implements Repository.
+// Deleted items are left out while unavailable ones are returned for the caller to reject.
 func (d *dbRepository) ListByUIDs(ctx context.Context, uids []string) ([]*MerchantItems, error) {
 	if len(uids) == 0 {
 		return make([]*MerchantItems, 0), nil
 	}
-	q := `
-		SELECT mi.uid, mi.name, mi.merchant_id, mi.item_category, mi.price, mi.image_url, mi.created_at
+	q := db.NewQuery(`
+		SELECT mi.id, mi.uid, mi.name, mi.merchant_id, m.uid, mi.item_category, mi.price, mi.image_url, mi.is_available, mi.created_at
 		FROM merchant_items mi
-		WHERE mi.uid IN(
-	`
-
-	for i, v := range uids {
-		if i > 0 {
-			q += ","
-		}
-		q += fmt.Sprintf("'%s'", v)
+		INNER JOIN merchants m ON m.id = mi.merchant_id
+	`)
+	q.Where("mi.deleted_at IS NULL").WhereAny("mi.uid", uids)
 
-	}
-
-	q += ");"
-	rows, err := d.db.DB().QueryContext(ctx, q)
+	query, args := q.Build()
+	rows, err := d.db.Executor(d.tx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -132,7 +161,7 @@ func (d *dbRepository) ListByUIDs(ctx context.Context, uids []string) ([]*Mercha
 	res := make([]*MerchantItems, 0)
 	for rows.Next() {
 		m := &MerchantItems{}
-		err = rows.Scan(&m.UID, &m.Name, &m.MerchantID, &m.Category, &m.Price, &m.ImageURL, &m.CreatedAt)
+		err = rows.Scan(&m.ID, &m.UID, &m.Name, &m.MerchantID, &m.MerchantUID, &m.Category, &m.Price, &m.ImageURL, &m.IsAvailable, &m.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -141,51 +170,157 @@ func (d *dbRepository) ListByUIDs(ctx context.Context, uids []string) ([]*Mercha
 	return res, nil
 }
 
-// ListByMerchantIDAndName implements Repository.
-func (d *dbRepository) ListByMerchantUIDAndName(ctx context.Context, merchantUIDs []string, name string) ([]*MerchantItems, error) {
-	if len(merchantUIDs) == 0 {
-		return make([]*MerchantItems, 0), nil
+// GetByUID implements Repository.
+func (d *dbRepository) GetByUID(ctx context.Context, merchantID uint64, uid string) (item *MerchantItems, err error) {
+	q := `
+		SELECT id, uid, name, merchant_id, item_category, price, image_url, is_available, created_at
+		FROM merchant_items
+		WHERE merchant_id = $1 AND uid = $2 AND deleted_at IS NULL;
+	`
+	row := d.db.Executor(d.tx).QueryRowContext(ctx, q, merchantID, uid)
+	m := &MerchantItems{}
+	err = row.Scan(&m.ID, &m.UID, &m.Name, &m.MerchantID, &m.Category, &m.Price, &m.ImageURL, &m.IsAvailable, &m.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrItemNotFound
 	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Update implements Repository.
+// Estimates keep the unit prices they were calculated with, so price changes only
+// affect new estimates.
+func (d *dbRepository) Update(ctx context.Context, item *MerchantItems) (err error) {
 	q := `
-		SELECT mi.uid, mi.name, mi.merchant_id, mi.item_category, mi.price, mi.image_url, mi.created_at
-		FROM merchant_items mi
-		JOIN merchants m on mi.merchant_id = m.id
-		WHERE m.uid IN(
+		UPDATE merchant_items
+		SET name = $1, item_category = $2, price = $3, image_url = $4, is_available = $5
+		WHERE id = $6 AND deleted_at IS NULL;
 	`
+	res, err := d.db.Executor(d.tx).ExecContext(ctx, q, item.Name, item.Category, item.Price, item.ImageURL, item.IsAvailable, item.ID)
+	if err != nil {
+		return
+	}
+	return expectOneRow(res)
+}
 
-	for i, v := range merchantUIDs {
-		if i > 0 {
-			q += ","
+// Delete implements Repository.
+// Items are only soft deleted so that historical orders keep resolving them.
+func (d *dbRepository) Delete(ctx context.Context, merchantID uint64, uid string) (err error) {
+	q := `
+		UPDATE merchant_items
+		SET deleted_at = current_timestamp
+		WHERE merchant_id = $1 AND uid = $2 AND deleted_at IS NULL;
+	`
+	res, err := d.db.Executor(d.tx).ExecContext(ctx, q, merchantID, uid)
+	if err != nil {
+		return
+	}
+	return expectOneRow(res)
+}
+
+// CreateOptionGroup implements Repository.
+// Run it in a transaction so the group is never seen without its options.
+func (d *dbRepository) CreateOptionGroup(ctx context.Context, group *OptionGroups) (err error) {
+	q := `
+		INSERT INTO item_option_groups (
+			uid, item_id, name, min_selections, max_selections
+		) VALUES (
+			$1, $2, $3, $4, $5
+		)
+		RETURNING id;
+	`
+	err = d.db.Executor(d.tx).QueryRowContext(ctx, q, group.UID, group.ItemID, group.Name, group.MinSelections, group.MaxSelections).Scan(&group.ID)
+	if err != nil {
+		return
+	}
+	q = `
+		INSERT INTO item_options (
+			uid, option_group_id, name, price_delta
+		) VALUES (
+			$1, $2, $3, $4
+		)
+		RETURNING id;
+	`
+	for i := range group.Options {
+		option := &group.Options[i]
+		option.OptionGroupID = group.ID
+		err = d.db.Executor(d.tx).QueryRowContext(ctx, q, option.UID, option.OptionGroupID, option.Name, option.PriceDelta).Scan(&option.ID)
+		if err != nil {
+			return
 		}
-		q += fmt.Sprintf("'%s'", v)
+	}
+	return
+}
 
+// DeleteOptionGroup implements Repository.
+func (d *dbRepository) DeleteOptionGroup(ctx context.Context, itemID uint64, uid string) (err error) {
+	q := `
+		DELETE FROM item_option_groups
+		WHERE item_id = $1 AND uid = $2;
+	`
+	res, err := d.db.Executor(d.tx).ExecContext(ctx, q, itemID, uid)
+	if err != nil {
+		return
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return
 	}
+	if rowsAffected == 0 {
+		return ErrOptionGroupNotFound
+	}
+	return
+}
 
-	if name != "" {
-		q += fmt.Sprintf(") AND mi.name ILIKE '%%%s%%';", name)
-	} else {
-		q += ");"
+// ListOptionGroupsByItemIDs implements Repository.
+func (d *dbRepository) ListOptionGroupsByItemIDs(ctx context.Context, itemIDs []uint64) (groups map[uint64][]OptionGroups, err error) {
+	groups = make(map[uint64][]OptionGroups)
+	if len(itemIDs) == 0 {
+		return
 	}
-	rows, err := d.db.DB().QueryContext(ctx, q)
+	ids := make([]int64, len(itemIDs))
+	for i, id := range itemIDs {
+		ids[i] = int64(id)
+	}
+	q := `
+		SELECT g.id, g.uid, g.item_id, g.name, g.min_selections, g.max_selections, o.id, o.uid, o.name, o.price_delta
+		FROM item_option_groups g
+		JOIN item_options o ON o.option_group_id = g.id
+		WHERE g.item_id = ANY($1)
+		ORDER BY g.item_id, g.id, o.id;
+	`
+	rows, err := d.db.Executor(d.tx).QueryContext(ctx, q, ids)
 	if err != nil {
-		return nil, err
+		return
 	}
 	defer rows.Close()
-	res := make([]*MerchantItems, 0)
 	for rows.Next() {
-		m := &MerchantItems{}
-		err = rows.Scan(&m.UID, &m.Name, &m.MerchantID, &m.Category, &m.Price, &m.ImageURL, &m.CreatedAt)
+		g := OptionGroups{}
+		o := Options{}
+		err = rows.Scan(&g.ID, &g.UID, &g.ItemID, &g.Name, &g.MinSelections, &g.MaxSelections, &o.ID, &o.UID, &o.Name, &o.PriceDelta)
 		if err != nil {
-			return nil, err
+			return
 		}
-		res = append(res, m)
+		o.OptionGroupID = g.ID
+		itemGroups := groups[g.ItemID]
+		if len(itemGroups) == 0 || itemGroups[len(itemGroups)-1].ID != g.ID {
+			itemGroups = append(itemGroups, g)
+		}
+		itemGroups[len(itemGroups)-1].Options = append(itemGroups[len(itemGroups)-1].Options, o)
+		groups[g.ItemID] = itemGroups
 	}
-	return res, nil
+	return
 }
 
-func whereOrAnd(paramNo int) string {
-	if paramNo == 1 {
-		return "WHERE "
+func expectOneRow(res sql.Result) error {
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrItemNotFound
 	}
-	return "AND "
+	return nil
 }
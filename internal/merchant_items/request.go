@@ -22,6 +22,14 @@ func (p CreateMerchantItemPayload) Validate() error {
 	)
 }
 
+type UpdateMerchantItemPayload struct {
+	Name            *string       `json:"name"`
+	ProductCategory *ItemCategory `json:"productCategory"`
+	Price           *int          `json:"price"`
+	ImageURL        *string       `json:"imageUrl"`
+	IsAvailable     *bool         `json:"isAvailable"`
+}
+
 type ListMerchantItemsPayload struct {
 	ItemUID         string       `schema:"itemId" binding:"omitempty"`
 	Name            string       `schema:"name" binding:"omitempty"`
@@ -29,6 +37,7 @@ type ListMerchantItemsPayload struct {
 	CreatedAtSort   string       `schema:"createdAt" binding:"omitempty"`
 	Limit           int          `schema:"limit" binding:"omitempty"`
 	Offset          int          `schema:"offset" binding:"omitempty"`
+	Cursor          *string      `schema:"cursor" binding:"omitempty"` // set, even empty, to page by cursor
 	MerchantUID     string
 	MerchantID      uint64
 }
@@ -42,3 +51,31 @@ func (p ListMerchantItemsPayload) Validate() error {
 		validation.Field(&p.MerchantUID, validation.Required),
 	)
 }
+
+type CreateOptionGroupPayload struct {
+	Name          string                `json:"name"`
+	MinSelections int                   `json:"minSelections"`
+	MaxSelections int                   `json:"maxSelections"`
+	Options       []CreateOptionPayload `json:"options"`
+}
+
+func (p CreateOptionGroupPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Name, validation.Required, validation.Length(MinOptionName, MaxOptionName)),
+		validation.Field(&p.MinSelections, validation.Min(0), validation.Max(p.MaxSelections)),
+		validation.Field(&p.MaxSelections, validation.Required, validation.Min(1), validation.Max(len(p.Options))),
+		validation.Field(&p.Options, validation.Required),
+	)
+}
+
+type CreateOptionPayload struct {
+	Name       string `json:"name"`
+	PriceDelta int    `json:"priceDelta"`
+}
+
+func (p CreateOptionPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Name, validation.Required, validation.Length(MinOptionName, MaxOptionName)),
+		validation.Field(&p.PriceDelta, validation.Min(0)),
+	)
+}
@@ -5,25 +5,73 @@ type MerchantItemUIDResponse struct {
 }
 
 type MerchantItemResponse struct {
-	UID             string       `json:"itemId"`
-	Name            string       `json:"name"`
-	ProductCategory ItemCategory `json:"productCategory"`
-	Price           int          `json:"price"`
-	ImageURL        string       `json:"imageUrl"`
-	CreatedAt       int          `json:"createdAt"`
+	UID             string                `json:"itemId"`
+	Name            string                `json:"name"`
+	ProductCategory ItemCategory          `json:"productCategory"`
+	Price           int                   `json:"price"`
+	ImageURL        string                `json:"imageUrl"`
+	IsAvailable     bool                  `json:"isAvailable"`
+	OptionGroups    []OptionGroupResponse `json:"optionGroups,omitempty"`
+	CreatedAt       int                   `json:"createdAt"`
 }
 
-func CreateMerchantItemListResponse(items []MerchantItems) []MerchantItemResponse {
+type OptionGroupResponse struct {
+	UID           string           `json:"optionGroupId"`
+	Name          string           `json:"name"`
+	MinSelections int              `json:"minSelections"`
+	MaxSelections int              `json:"maxSelections"`
+	Options       []OptionResponse `json:"options"`
+}
+
+type OptionResponse struct {
+	UID        string `json:"optionId"`
+	Name       string `json:"name"`
+	PriceDelta int    `json:"priceDelta"`
+}
+
+func CreateMerchantItemResponse(item MerchantItems, groups []OptionGroups) MerchantItemResponse {
+	return MerchantItemResponse{
+		UID:             item.UID,
+		Name:            item.Name,
+		ProductCategory: item.Category,
+		Price:           item.Price,
+		ImageURL:        item.ImageURL,
+		IsAvailable:     item.IsAvailable,
+		OptionGroups:    CreateOptionGroupListResponse(groups),
+		CreatedAt:       item.CreatedAt.Nanosecond(),
+	}
+}
+
+func CreateMerchantItemListResponse(items []MerchantItems, groups map[uint64][]OptionGroups) []MerchantItemResponse {
 	itemsResponse := make([]MerchantItemResponse, 0)
 	for _, item := range items {
-		itemsResponse = append(itemsResponse, MerchantItemResponse{
-			UID:             item.UID,
-			Name:            item.Name,
-			ProductCategory: item.Category,
-			Price:           item.Price,
-			ImageURL:        item.ImageURL,
-			CreatedAt:       item.CreatedAt.Nanosecond(),
-		})
+		itemsResponse = append(itemsResponse, CreateMerchantItemResponse(item, groups[item.ID]))
 	}
 	return itemsResponse
 }
+
+func CreateOptionGroupResponse(group OptionGroups) OptionGroupResponse {
+	res := OptionGroupResponse{
+		UID:           group.UID,
+		Name:          group.Name,
+		MinSelections: group.MinSelections,
+		MaxSelections: group.MaxSelections,
+		Options:       make([]OptionResponse, 0, len(group.Options)),
+	}
+	for _, option := range group.Options {
+		res.Options = append(res.Options, OptionResponse{
+			UID:        option.UID,
+			Name:       option.Name,
+			PriceDelta: option.PriceDelta,
+		})
+	}
+	return res
+}
+
+func CreateOptionGroupListResponse(groups []OptionGroups) []OptionGroupResponse {
+	res := make([]OptionGroupResponse, 0, len(groups))
+	for _, group := range groups {
+		res = append(res, CreateOptionGroupResponse(group))
+	}
+	return res
+}
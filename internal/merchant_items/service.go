@@ -2,8 +2,12 @@ package merchantitems
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 
+	"github.com/citadel-corp/belimang/internal/common/db"
 	"github.com/citadel-corp/belimang/internal/common/id"
+	"github.com/citadel-corp/belimang/internal/common/permission"
 	"github.com/citadel-corp/belimang/internal/common/response"
 	"github.com/citadel-corp/belimang/internal/merchants"
 )
@@ -11,15 +15,20 @@ import (
 type Service interface {
 	Create(ctx context.Context, payload CreateMerchantItemPayload) (resp *MerchantItemUIDResponse, err error)
 	List(ctx context.Context, payload ListMerchantItemsPayload) (resp []MerchantItemResponse, pagination *response.Pagination, err error)
+	Update(ctx context.Context, merchantUID string, itemUID string, payload UpdateMerchantItemPayload) (resp *MerchantItemResponse, err error)
+	Delete(ctx context.Context, merchantUID string, itemUID string) (err error)
+	CreateOptionGroup(ctx context.Context, merchantUID string, itemUID string, payload CreateOptionGroupPayload) (resp *OptionGroupResponse, err error)
+	DeleteOptionGroup(ctx context.Context, merchantUID string, itemUID string, optionGroupUID string) (err error)
 }
 
 type merchantItemService struct {
+	transactor         db.Transactor
 	repository         Repository
 	merchantRepository merchants.Repository
 }
 
-func NewService(repository Repository, merchantRepository merchants.Repository) Service {
-	return &merchantItemService{repository: repository, merchantRepository: merchantRepository}
+func NewService(transactor db.Transactor, repository Repository, merchantRepository merchants.Repository) Service {
+	return &merchantItemService{transactor: transactor, repository: repository, merchantRepository: merchantRepository}
 }
 
 func (s *merchantItemService) Create(ctx context.Context, payload CreateMerchantItemPayload) (resp *MerchantItemUIDResponse, err error) {
@@ -28,6 +37,10 @@ func (s *merchantItemService) Create(ctx context.Context, payload CreateMerchant
 	if err != nil {
 		return
 	}
+	err = merchants.Authorize(ctx, s.merchantRepository, merchant, permission.Write)
+	if err != nil {
+		return
+	}
 
 	item := &MerchantItems{
 		UID:        id.GenerateStringID(16),
@@ -55,6 +68,10 @@ func (s *merchantItemService) List(ctx context.Context, payload ListMerchantItem
 	if err != nil {
 		return
 	}
+	err = merchants.Authorize(ctx, s.merchantRepository, merchant, permission.Read)
+	if err != nil {
+		return
+	}
 	payload.MerchantID = merchant.ID
 
 	if payload.Limit == 0 {
@@ -65,6 +82,149 @@ func (s *merchantItemService) List(ctx context.Context, payload ListMerchantItem
 	if err != nil {
 		return
 	}
+	itemIDs := make([]uint64, 0, len(merchantItems))
+	for _, item := range merchantItems {
+		itemIDs = append(itemIDs, item.ID)
+	}
+	groups, err := s.repository.ListOptionGroupsByItemIDs(ctx, itemIDs)
+	if err != nil {
+		return
+	}
+
+	return CreateMerchantItemListResponse(merchantItems, groups), pagination, nil
+}
+
+func (s *merchantItemService) Update(ctx context.Context, merchantUID string, itemUID string, payload UpdateMerchantItemPayload) (resp *MerchantItemResponse, err error) {
+	// get merchant
+	merchant, err := s.merchantRepository.GetByUID(ctx, merchantUID)
+	if err != nil {
+		return
+	}
+	err = merchants.Authorize(ctx, s.merchantRepository, merchant, permission.Write)
+	if err != nil {
+		return
+	}
+	item, err := s.repository.GetByUID(ctx, merchant.ID, itemUID)
+	if err != nil {
+		return
+	}
+
+	// fields left out keep their current value, the result is validated like a new item
+	merged := CreateMerchantItemPayload{
+		Name:            item.Name,
+		ProductCategory: item.Category,
+		Price:           item.Price,
+		ImageURL:        item.ImageURL,
+	}
+	if payload.Name != nil {
+		merged.Name = *payload.Name
+	}
+	if payload.ProductCategory != nil {
+		merged.ProductCategory = *payload.ProductCategory
+	}
+	if payload.Price != nil {
+		merged.Price = *payload.Price
+	}
+	if payload.ImageURL != nil {
+		merged.ImageURL = *payload.ImageURL
+	}
+	err = merged.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+
+	item.Name = merged.Name
+	item.Category = merged.ProductCategory
+	item.Price = merged.Price
+	item.ImageURL = merged.ImageURL
+	if payload.IsAvailable != nil {
+		item.IsAvailable = *payload.IsAvailable
+	}
+	err = s.repository.Update(ctx, item)
+	if err != nil {
+		return
+	}
+
+	groups, err := s.repository.ListOptionGroupsByItemIDs(ctx, []uint64{item.ID})
+	if err != nil {
+		return
+	}
+
+	itemResp := CreateMerchantItemResponse(*item, groups[item.ID])
+	return &itemResp, nil
+}
+
+func (s *merchantItemService) Delete(ctx context.Context, merchantUID string, itemUID string) (err error) {
+	// get merchant
+	merchant, err := s.merchantRepository.GetByUID(ctx, merchantUID)
+	if err != nil {
+		return
+	}
+	err = merchants.Authorize(ctx, s.merchantRepository, merchant, permission.Write)
+	if err != nil {
+		return
+	}
+	return s.repository.Delete(ctx, merchant.ID, itemUID)
+}
 
-	return CreateMerchantItemListResponse(merchantItems), pagination, nil
+func (s *merchantItemService) CreateOptionGroup(ctx context.Context, merchantUID string, itemUID string, payload CreateOptionGroupPayload) (resp *OptionGroupResponse, err error) {
+	err = payload.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	// get merchant
+	merchant, err := s.merchantRepository.GetByUID(ctx, merchantUID)
+	if err != nil {
+		return
+	}
+	err = merchants.Authorize(ctx, s.merchantRepository, merchant, permission.Write)
+	if err != nil {
+		return
+	}
+	item, err := s.repository.GetByUID(ctx, merchant.ID, itemUID)
+	if err != nil {
+		return
+	}
+
+	group := &OptionGroups{
+		UID:           id.GenerateStringID(16),
+		ItemID:        item.ID,
+		Name:          payload.Name,
+		MinSelections: payload.MinSelections,
+		MaxSelections: payload.MaxSelections,
+		Options:       make([]Options, 0, len(payload.Options)),
+	}
+	for _, option := range payload.Options {
+		group.Options = append(group.Options, Options{
+			UID:        id.GenerateStringID(16),
+			Name:       option.Name,
+			PriceDelta: option.PriceDelta,
+		})
+	}
+	err = s.transactor.StartTx(ctx, func(tx *sql.Tx) error {
+		return s.repository.WithTx(tx).CreateOptionGroup(ctx, group)
+	})
+	if err != nil {
+		return
+	}
+
+	groupResp := CreateOptionGroupResponse(*group)
+	return &groupResp, nil
+}
+
+func (s *merchantItemService) DeleteOptionGroup(ctx context.Context, merchantUID string, itemUID string, optionGroupUID string) (err error) {
+	// get merchant
+	merchant, err := s.merchantRepository.GetByUID(ctx, merchantUID)
+	if err != nil {
+		return
+	}
+	err = merchants.Authorize(ctx, s.merchantRepository, merchant, permission.Write)
+	if err != nil {
+		return
+	}
+	item, err := s.repository.GetByUID(ctx, merchant.ID, itemUID)
+	if err != nil {
+		return
+	}
+	return s.repository.DeleteOptionGroup(ctx, item.ID, optionGroupUID)
 }
@@ -0,0 +1,40 @@
+package merchants
+
+import (
+	"context"
+
+	"github.com/citadel-corp/belimang/internal/common/middleware"
+	"github.com/citadel-corp/belimang/internal/common/permission"
+)
+
+// Authorize checks that the user of ctx may act on the merchant, action being
+// permission.Read or permission.Write. The user needs the permission, on every
+// merchant or on this one, or to be a member of the merchant. Membership is
+// looked up rather than carried in the token, so removing a member takes effect
+// at once.
+func Authorize(ctx context.Context, repository Repository, merchant *Merchants, action string) error {
+	claims, ok := middleware.GetClaims(ctx)
+	if !ok {
+		return ErrForbidden
+	}
+	if permission.Grants(claims.Permissions, permission.New(permission.Merchant, action, merchant.UID)) {
+		return nil
+	}
+	member, err := repository.IsMember(ctx, merchant.ID, claims.UserUID)
+	if err != nil {
+		return err
+	}
+	if !member {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// authorizeAny checks that the user of ctx may act on every merchant.
+func authorizeAny(ctx context.Context, action string) error {
+	claims, ok := middleware.GetClaims(ctx)
+	if !ok || !permission.Grants(claims.Permissions, permission.New(permission.Merchant, action, permission.Any)) {
+		return ErrForbidden
+	}
+	return nil
+}
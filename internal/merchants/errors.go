@@ -5,4 +5,9 @@ import "errors"
 var (
 	ErrMerchantNotFound = errors.New("merchant not found")
 	ErrValidationFailed = errors.New("validation failed")
+	ErrClosureNotFound  = errors.New("closure not found")
+	ErrClosureExists    = errors.New("merchant is already closed on this date")
+	ErrForbidden        = errors.New("not a member of the merchant")
+	ErrUserNotFound     = errors.New("user not found")
+	ErrMemberNotFound   = errors.New("member not found")
 )
@@ -1,6 +1,7 @@
 package merchants
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/citadel-corp/belimang/internal/common/request"
@@ -70,6 +71,13 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	}
 
 	merchantsResp, pagination, err := h.service.List(r.Context(), req)
+	if errors.Is(err, response.ErrInvalidCursor) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
 			Message: "Internal server error",
@@ -107,6 +115,13 @@ func (h *Handler) ListByDistance(w http.ResponseWriter, r *http.Request) {
 	}
 
 	merchantsResp, pagination, err := h.service.ListByDistance(r.Context(), req)
+	if errors.Is(err, response.ErrInvalidCursor) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
 			Message: "Internal server error",
@@ -119,3 +134,393 @@ func (h *Handler) ListByDistance(w http.ResponseWriter, r *http.Request) {
 		Meta: pagination,
 	})
 }
+
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	var req UpdateMerchantPayload
+
+	err := request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	merchantResp, err := h.service.Update(r.Context(), mux.Vars(r)["merchantId"], req)
+	if errors.Is(err, ErrValidationFailed) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrMerchantNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Merchant not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrForbidden) {
+		response.JSON(w, http.StatusForbidden, response.ResponseBody{
+			Message: "Forbidden",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, merchantResp)
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	err := h.service.Delete(r.Context(), mux.Vars(r)["merchantId"])
+	if errors.Is(err, ErrMerchantNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Merchant not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "Merchant deleted successfully",
+	})
+}
+
+func (h *Handler) Restore(w http.ResponseWriter, r *http.Request) {
+	err := h.service.Restore(r.Context(), mux.Vars(r)["merchantId"])
+	if errors.Is(err, ErrMerchantNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Deleted merchant not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "Merchant restored successfully",
+	})
+}
+
+func (h *Handler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	scheduleResp, err := h.service.GetSchedule(r.Context(), mux.Vars(r)["merchantId"])
+	if errors.Is(err, ErrMerchantNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Merchant not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrForbidden) {
+		response.JSON(w, http.StatusForbidden, response.ResponseBody{
+			Message: "Forbidden",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, scheduleResp)
+}
+
+func (h *Handler) UpdateOpeningHours(w http.ResponseWriter, r *http.Request) {
+	var req UpdateOpeningHoursPayload
+
+	err := request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	scheduleResp, err := h.service.UpdateOpeningHours(r.Context(), mux.Vars(r)["merchantId"], req)
+	if errors.Is(err, ErrValidationFailed) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrMerchantNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Merchant not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrForbidden) {
+		response.JSON(w, http.StatusForbidden, response.ResponseBody{
+			Message: "Forbidden",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, scheduleResp)
+}
+
+func (h *Handler) CreateClosure(w http.ResponseWriter, r *http.Request) {
+	var req CreateClosurePayload
+
+	err := request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	scheduleResp, err := h.service.CreateClosure(r.Context(), mux.Vars(r)["merchantId"], req)
+	if errors.Is(err, ErrValidationFailed) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrMerchantNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Merchant not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrClosureExists) {
+		response.JSON(w, http.StatusConflict, response.ResponseBody{
+			Message: "Conflict",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrForbidden) {
+		response.JSON(w, http.StatusForbidden, response.ResponseBody{
+			Message: "Forbidden",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusCreated, scheduleResp)
+}
+
+func (h *Handler) DeleteClosure(w http.ResponseWriter, r *http.Request) {
+	err := h.service.DeleteClosure(r.Context(), mux.Vars(r)["merchantId"], mux.Vars(r)["date"])
+	if errors.Is(err, ErrValidationFailed) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrMerchantNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Merchant not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrClosureNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Closure not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrForbidden) {
+		response.JSON(w, http.StatusForbidden, response.ResponseBody{
+			Message: "Forbidden",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "Closure deleted successfully",
+	})
+}
+
+func (h *Handler) ListMine(w http.ResponseWriter, r *http.Request) {
+	merchantsResp, err := h.service.ListMine(r.Context())
+	if errors.Is(err, ErrForbidden) {
+		response.JSON(w, http.StatusForbidden, response.ResponseBody{
+			Message: "Forbidden",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "Success",
+		Data:    merchantsResp,
+	})
+}
+
+func (h *Handler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	membersResp, err := h.service.ListMembers(r.Context(), mux.Vars(r)["merchantId"])
+	if errors.Is(err, ErrMerchantNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Merchant not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrForbidden) {
+		response.JSON(w, http.StatusForbidden, response.ResponseBody{
+			Message: "Forbidden",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "Success",
+		Data:    membersResp,
+	})
+}
+
+func (h *Handler) AddMember(w http.ResponseWriter, r *http.Request) {
+	var req AddMemberPayload
+
+	err := request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	membersResp, err := h.service.AddMember(r.Context(), mux.Vars(r)["merchantId"], req)
+	if errors.Is(err, ErrValidationFailed) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrMerchantNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Merchant not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrUserNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "User not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrForbidden) {
+		response.JSON(w, http.StatusForbidden, response.ResponseBody{
+			Message: "Forbidden",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusCreated, response.ResponseBody{
+		Message: "Member added successfully",
+		Data:    membersResp,
+	})
+}
+
+func (h *Handler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	err := h.service.RemoveMember(r.Context(), vars["merchantId"], vars["userId"])
+	if errors.Is(err, ErrMerchantNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Merchant not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrMemberNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Member not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrForbidden) {
+		response.JSON(w, http.StatusForbidden, response.ResponseBody{
+			Message: "Forbidden",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "Member removed successfully",
+	})
+}
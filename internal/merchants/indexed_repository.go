@@ -0,0 +1,237 @@
+package merchants
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/citadel-corp/belimang/internal/common/db"
+	"github.com/citadel-corp/belimang/internal/common/env"
+	"github.com/citadel-corp/belimang/internal/common/geoindex"
+	"github.com/citadel-corp/belimang/internal/common/response"
+)
+
+// GeoIndexConfig turns on answering nearby searches from memory.
+type GeoIndexConfig struct {
+	Enabled         bool
+	Precision       int           // geohash length of the grid cells
+	RefreshInterval time.Duration // how often to reload from the database, never when zero
+}
+
+func LoadGeoIndexConfig() (GeoIndexConfig, error) {
+	var (
+		cfg = GeoIndexConfig{Precision: geoindex.DefaultPrecision}
+		err error
+	)
+	if cfg.Enabled, err = env.Bool("MERCHANT_GEOINDEX", cfg.Enabled); err != nil {
+		return cfg, err
+	}
+	if cfg.Precision, err = env.Int("MERCHANT_GEOINDEX_PRECISION", cfg.Precision); err != nil {
+		return cfg, err
+	}
+	if cfg.Precision < 1 || cfg.Precision > 12 {
+		return cfg, errors.New("MERCHANT_GEOINDEX_PRECISION must be between 1 and 12")
+	}
+	if cfg.RefreshInterval, err = env.Duration("MERCHANT_GEOINDEX_REFRESH", cfg.RefreshInterval); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// IndexedRepository is a Repository answering nearby searches from an in-memory
+// geohash grid of the merchants, and everything else from the wrapped
+// repository. Writes go through the wrapped repository and then to the grid, so
+// it only sees the writes of its own process; other instances catch up on
+// Refresh.
+type IndexedRepository struct {
+	Repository
+	index     *geoindex.Index[Merchants]
+	refreshMu *sync.Mutex // keeps refreshes from overlapping
+	tx        *sql.Tx
+}
+
+// NewIndexedRepository wraps repository and loads every merchant into the grid.
+func NewIndexedRepository(ctx context.Context, repository Repository, precision int) (*IndexedRepository, error) {
+	r := &IndexedRepository{
+		Repository: repository,
+		index:      geoindex.New[Merchants](precision),
+		refreshMu:  &sync.Mutex{},
+	}
+	if err := r.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Refresh reloads the grid from the wrapped repository. Writes committed while
+// it loads are kept in the grid rather than overwritten by the load.
+func (r *IndexedRepository) Refresh(ctx context.Context) error {
+	r.refreshMu.Lock()
+	defer r.refreshMu.Unlock()
+	since := r.index.Version()
+	merchants, err := r.Repository.ListAll(ctx)
+	if err != nil {
+		return err
+	}
+	points := make([]geoindex.Point[Merchants], 0, len(merchants))
+	for _, m := range merchants {
+		points = append(points, merchantPoint(m))
+	}
+	r.index.ReplaceSince(since, points)
+	return nil
+}
+
+// Len returns the number of merchants in the grid.
+func (r *IndexedRepository) Len() int {
+	return r.index.Len()
+}
+
+// WithTx implements Repository.
+// The writes made in tx reach the grid once tx commits, and never if it rolls
+// back.
+func (r *IndexedRepository) WithTx(tx *sql.Tx) Repository {
+	return &IndexedRepository{Repository: r.Repository.WithTx(tx), index: r.index, refreshMu: r.refreshMu, tx: tx}
+}
+
+// Create implements Repository.
+func (r *IndexedRepository) Create(ctx context.Context, merchant *Merchants) (err error) {
+	if err = r.Repository.Create(ctx, merchant); err != nil {
+		return
+	}
+	// the id and creation time are only known to the database
+	return r.reload(ctx, merchant.UID)
+}
+
+// Update implements Repository.
+func (r *IndexedRepository) Update(ctx context.Context, merchant *Merchants) (err error) {
+	if err = r.Repository.Update(ctx, merchant); err != nil {
+		return
+	}
+	return r.reload(ctx, merchant.UID)
+}
+
+// Delete implements Repository.
+func (r *IndexedRepository) Delete(ctx context.Context, uid string) (err error) {
+	if err = r.Repository.Delete(ctx, uid); err != nil {
+		return
+	}
+	db.AfterCommit(r.tx, func() { r.index.Remove(uid) })
+	return
+}
+
+// Restore implements Repository.
+func (r *IndexedRepository) Restore(ctx context.Context, uid string) (err error) {
+	if err = r.Repository.Restore(ctx, uid); err != nil {
+		return
+	}
+	return r.reload(ctx, uid)
+}
+
+// ListByDistance implements Repository.
+// The grid knows neither item names nor opening hours, so searches by name or
+// for merchants open now go to the wrapped repository.
+func (r *IndexedRepository) ListByDistance(ctx context.Context, filter ListMerchantsByDistancePayload) (merchantWithItem []MerchantsWithItem, pagination *response.Pagination, err error) {
+	lat, latErr := strconv.ParseFloat(filter.Lat, 64)
+	lng, lngErr := strconv.ParseFloat(filter.Lng, 64)
+	if filter.Name != "" || filter.OpenNow || latErr != nil || lngErr != nil {
+		return r.Repository.ListByDistance(ctx, filter)
+	}
+
+	var after *response.Cursor
+	if filter.Cursor != nil {
+		after, err = response.DecodeDistanceCursor(*filter.Cursor)
+		if err != nil {
+			return
+		}
+	}
+	accept := func(res geoindex.Result[Merchants]) bool {
+		m := res.Value
+		if filter.MerchantUID != "" && m.UID != filter.MerchantUID {
+			return false
+		}
+		if filter.MerchantCategory != "" && m.Category != filter.MerchantCategory {
+			return false
+		}
+		if after != nil && (res.Distance < *after.Distance || res.Distance == *after.Distance && m.UID <= after.ID) {
+			return false
+		}
+		return true
+	}
+
+	pagination = &response.Pagination{}
+	pagination.Limit = filter.Limit
+	var page []geoindex.Result[Merchants]
+	if filter.Cursor != nil {
+		// one extra merchant tells whether there is a next page
+		page = r.index.Nearest(lat, lng, filter.Limit+1, filter.MaxDistance, accept)
+		if len(page) > filter.Limit {
+			page = page[:filter.Limit]
+			last := page[len(page)-1]
+			pagination.NextCursor = response.Cursor{Distance: &last.Distance, ID: last.ID}.Encode()
+		}
+	} else {
+		// counting every match takes finding them all, so only withTotal does
+		n := filter.Offset + filter.Limit
+		if filter.WithTotal {
+			n = math.MaxInt
+		}
+		all := r.index.Nearest(lat, lng, n, filter.MaxDistance, accept)
+		pagination.Offset = filter.Offset
+		if filter.WithTotal {
+			pagination.Total = len(all)
+		}
+		page = all[min(filter.Offset, len(all)):min(filter.Offset+filter.Limit, len(all))]
+	}
+
+	merchantIDs := make([]uint64, 0, len(page))
+	for _, res := range page {
+		merchantIDs = append(merchantIDs, res.Value.ID)
+	}
+	items, err := r.Repository.ListItemsByMerchantIDs(ctx, merchantIDs)
+	if err != nil {
+		return
+	}
+
+	merchantWithItem = make([]MerchantsWithItem, 0, len(page))
+	for _, res := range page {
+		m := res.Value
+		merchantItems := items[m.ID]
+		if len(merchantItems) == 0 {
+			// like the LEFT JOIN, a merchant without items still takes a row
+			merchantItems = []MerchantItems{{}}
+		}
+		for _, mi := range merchantItems {
+			merchantWithItem = append(merchantWithItem, MerchantsWithItem{
+				ID:        m.ID,
+				UID:       m.UID,
+				Name:      m.Name,
+				Category:  m.Category,
+				ImageURL:  m.ImageURL,
+				Lat:       m.Lat,
+				Lng:       m.Lng,
+				Distance:  res.Distance,
+				CreatedAt: m.CreatedAt,
+				Item:      mi,
+			})
+		}
+	}
+	return
+}
+
+// reload puts the merchant with the uid, as stored, into the grid.
+func (r *IndexedRepository) reload(ctx context.Context, uid string) error {
+	merchant, err := r.Repository.GetByUID(ctx, uid)
+	if err != nil {
+		return err
+	}
+	db.AfterCommit(r.tx, func() { r.index.Put(merchantPoint(*merchant)) })
+	return nil
+}
+
+func merchantPoint(m Merchants) geoindex.Point[Merchants] {
+	return geoindex.Point[Merchants]{ID: m.UID, Lat: m.Lat, Lng: m.Lng, Value: m}
+}
@@ -2,16 +2,59 @@ package merchants
 
 import (
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 )
 
 type MerchantItems struct {
-	ID          uint64
-	UID         string
-	MerchantID  uint64
-	MerchantUID string
-	Name        string
-	Category    sql.NullString
-	Price       int
-	ImageURL    string
-	CreatedAt   sql.NullTime
+	ID           uint64
+	UID          string
+	MerchantID   uint64
+	MerchantUID  string
+	Name         string
+	Category     sql.NullString
+	Price        int
+	ImageURL     string
+	IsAvailable  bool
+	OptionGroups ItemOptionGroups
+	CreatedAt    sql.NullTime
+}
+
+// ItemOptionGroups are the option groups of an item, aggregated as JSON by the query.
+type ItemOptionGroups []ItemOptionGroupResponse
+
+type ItemOptionGroupResponse struct {
+	UID           string               `json:"optionGroupId"`
+	Name          string               `json:"name"`
+	MinSelections int                  `json:"minSelections"`
+	MaxSelections int                  `json:"maxSelections"`
+	Options       []ItemOptionResponse `json:"options"`
+}
+
+type ItemOptionResponse struct {
+	UID        string `json:"optionId"`
+	Name       string `json:"name"`
+	PriceDelta int    `json:"priceDelta"`
+}
+
+// Make the Attrs struct implement the driver.Valuer interface. This method
+// simply returns the JSON-encoded representation of the struct.
+func (a ItemOptionGroups) Value() (driver.Value, error) {
+	return json.Marshal(a)
+}
+
+// Make the Attrs struct implement the sql.Scanner interface. This method
+// simply decodes a JSON-encoded value into the struct fields.
+func (a *ItemOptionGroups) Scan(value interface{}) error {
+	if value == nil {
+		*a = ItemOptionGroups{}
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(b, &a)
 }
@@ -3,10 +3,13 @@ package merchants
 import "time"
 
 var (
-	MinName = 2
-	MaxName = 30
+	MinName          = 2
+	MaxName          = 30
+	MaxClosureReason = 100
 )
 
+const DateFormat = "2006-01-02"
+
 type MerchantCategory string
 
 var (
@@ -18,6 +21,8 @@ var (
 	ConvenienceStore      MerchantCategory = "ConvenienceStore"
 )
 
+var DaysOfWeek = []interface{}{0, 1, 2, 3, 4, 5, 6}
+
 var MerchantCategories = []interface{}{
 	SmallRestaurant,
 	MediumRestaurant,
@@ -35,6 +40,15 @@ type Merchants struct {
 	ImageURL  string
 	Lat       float64
 	Lng       float64
+	Timezone  string
+	CreatedAt time.Time
+}
+
+// Member is a user linked to a merchant as its staff.
+type Member struct {
+	UserUID   string
+	Username  string
+	Email     string
 	CreatedAt time.Time
 }
 
@@ -46,6 +60,9 @@ type MerchantsWithItem struct {
 	ImageURL  string
 	Lat       float64
 	Lng       float64
+	Distance  float64 // meters from the searched location
 	CreatedAt time.Time
 	Item      MerchantItems
+
+	DeliveryMinutes int // estimated by the service, not stored
 }
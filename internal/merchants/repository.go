@@ -3,29 +3,53 @@ package merchants
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"strings"
+	"time"
 
 	"github.com/citadel-corp/belimang/internal/common/db"
 	"github.com/citadel-corp/belimang/internal/common/response"
+	"github.com/citadel-corp/belimang/internal/common/textsearch"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type Repository interface {
+	WithTx(tx *sql.Tx) Repository
 	Create(ctx context.Context, merchant *Merchants) (err error)
 	ListByUIDs(ctx context.Context, ids []string) ([]*Merchants, error)
 	List(ctx context.Context, filter ListMerchantsPayload) (merchants []Merchants, pagination *response.Pagination, err error)
 	GetByUID(ctx context.Context, uid string) (merchant *Merchants, err error)
 	ListByDistance(ctx context.Context, filter ListMerchantsByDistancePayload) (merchantWithItem []MerchantsWithItem, pagination *response.Pagination, err error)
+	Update(ctx context.Context, merchant *Merchants) (err error)
+	Delete(ctx context.Context, uid string) (err error)
+	Restore(ctx context.Context, uid string) (err error)
+	ListAll(ctx context.Context) (merchants []Merchants, err error)
+	ListItemsByMerchantIDs(ctx context.Context, merchantIDs []uint64) (items map[uint64][]MerchantItems, err error)
+	IsMember(ctx context.Context, merchantID uint64, userUID string) (member bool, err error)
+	AddMember(ctx context.Context, merchantID uint64, userUID string) (err error)
+	RemoveMember(ctx context.Context, merchantID uint64, userUID string) (err error)
+	ListMembers(ctx context.Context, merchantID uint64) (members []Member, err error)
+	ListByMember(ctx context.Context, userUID string) (merchants []Merchants, err error)
+	ListSchedules(ctx context.Context, merchantIDs []uint64) (schedules map[uint64]*Schedule, err error)
+	ReplaceOpeningHours(ctx context.Context, merchantID uint64, timezone string, openingHours []OpeningHours) (err error)
+	InsertClosure(ctx context.Context, merchantID uint64, closure Closure) (err error)
+	DeleteClosure(ctx context.Context, merchantID uint64, date time.Time) (err error)
 }
 
 type dbRepository struct {
 	db *db.DB
+	tx *sql.Tx
 }
 
 func NewRepository(db *db.DB) Repository {
 	return &dbRepository{db: db}
 }
 
+// WithTx implements Repository.
+func (d *dbRepository) WithTx(tx *sql.Tx) Repository {
+	return &dbRepository{db: d.db, tx: tx}
+}
+
 func (d *dbRepository) Create(ctx context.Context, merchant *Merchants) (err error) {
 	createMerchantQuery := `
 	    INSERT INTO merchants (
@@ -34,7 +58,7 @@ func (d *dbRepository) Create(ctx context.Context, merchant *Merchants) (err err
             $1, $2, $3, $4, $5, $6
         )
 	`
-	_, err = d.db.DB().ExecContext(ctx, createMerchantQuery, merchant.UID, merchant.Name, merchant.Category, merchant.ImageURL, merchant.Lat, merchant.Lng)
+	_, err = d.db.Executor(d.tx).ExecContext(ctx, createMerchantQuery, merchant.UID, merchant.Name, merchant.Category, merchant.ImageURL, merchant.Lat, merchant.Lng)
 	if err != nil {
 		return
 	}
@@ -43,21 +67,14 @@ func (d *dbRepository) Create(ctx context.Context, merchant *Merchants) (err err
 
 // ListByUIDs implements Repository.
 func (d *dbRepository) ListByUIDs(ctx context.Context, ids []string) ([]*Merchants, error) {
-	q := `
-	    SELECT id, uid, name, merchant_category, image_url, location_lat, location_lng
+	q := db.NewQuery(`
+	    SELECT id, uid, name, merchant_category, image_url, location_lat, location_lng, created_at
 		FROM merchants
-		WHERE uid IN (
-	`
-	for i, v := range ids {
-		if i > 0 {
-			q += ","
-		}
-		q += fmt.Sprintf("'%s'", v)
-
-	}
+	`)
+	q.Where("deleted_at IS NULL").WhereAny("uid", ids)
 
-	q += ");"
-	rows, err := d.db.DB().QueryContext(ctx, q)
+	query, args := q.Build()
+	rows, err := d.db.Executor(d.tx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -65,7 +82,7 @@ func (d *dbRepository) ListByUIDs(ctx context.Context, ids []string) ([]*Merchan
 	res := make([]*Merchants, 0)
 	for rows.Next() {
 		m := &Merchants{}
-		err := rows.Scan(&m.ID, &m.UID, &m.Name, &m.Category, &m.ImageURL, &m.Lat, &m.Lng)
+		err := rows.Scan(&m.ID, &m.UID, &m.Name, &m.Category, &m.ImageURL, &m.Lat, &m.Lng, &m.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -77,29 +94,25 @@ func (d *dbRepository) ListByUIDs(ctx context.Context, ids []string) ([]*Merchan
 func (d *dbRepository) List(ctx context.Context, filter ListMerchantsPayload) (merchants []Merchants, pagination *response.Pagination, err error) {
 	merchants = make([]Merchants, 0)
 
-	q := `
-		SELECT COUNT(*) OVER() AS total_count, m.uid, m.name, m.merchant_category, m.image_url, m.location_lat, m.location_lng, m.created_at
+	totalCount := "COUNT(*) OVER()"
+	if filter.Cursor != nil {
+		// counting every match is what slows down deep pages, so cursor pages skip it
+		totalCount = "0"
+	}
+	q := db.NewQuery(fmt.Sprintf(`
+		SELECT %s AS total_count, m.id, m.uid, m.name, m.merchant_category, m.image_url, m.location_lat, m.location_lng, m.created_at
 		FROM merchants m
-	`
-
-	paramNo := 1
-	params := make([]interface{}, 0)
+	`, totalCount))
+	q.Where("m.deleted_at IS NULL")
 	if filter.MerchantUID != "" {
-		q += fmt.Sprintf("WHERE m.uid = $%d ", paramNo)
-		paramNo += 1
-		params = append(params, filter.MerchantUID)
+		q.Where("m.uid = ?", filter.MerchantUID)
 	}
-	if filter.Name != "" {
-		q += whereOrAnd(paramNo, 1)
-		q += fmt.Sprintf("LOWER(m.name) LIKE $%d ", paramNo)
-		paramNo += 1
-		params = append(params, "%"+strings.ToLower(filter.Name)+"%")
+	search := textsearch.Parse(filter.Name)
+	if !search.IsEmpty() {
+		q.Where(textsearch.Condition("m.name", "m.search_vector"), search.ConditionArgs()...)
 	}
 	if filter.MerchantCategory != "" {
-		q += whereOrAnd(paramNo, 1)
-		q += fmt.Sprintf("m.merchant_category = $%d ", paramNo)
-		paramNo += 1
-		params = append(params, filter.MerchantCategory)
+		q.Where("m.merchant_category = ?", filter.MerchantCategory)
 	}
 
 	orderBy := "desc"
@@ -107,97 +120,172 @@ func (d *dbRepository) List(ctx context.Context, filter ListMerchantsPayload) (m
 		orderBy = "asc"
 	}
 
-	q += fmt.Sprintf(" ORDER BY m.created_at %s", orderBy)
-
-	q += fmt.Sprintf(" OFFSET $%d LIMIT $%d", paramNo, paramNo+1)
-	params = append(params, filter.Offset)
-	params = append(params, filter.Limit)
+	if filter.Cursor != nil {
+		var after *response.Cursor
+		after, err = response.DecodeCreatedAtCursor(*filter.Cursor)
+		if err != nil {
+			return
+		}
+		if after != nil {
+			op := "<"
+			if orderBy == "asc" {
+				op = ">"
+			}
+			q.Where("(m.created_at, m.uid) "+op+" (?, ?)", *after.CreatedAt, after.ID)
+		}
+		// cursor pages keep to the created at order, a relevance rank being no stable key
+		q.OrderBy("m.created_at " + orderBy).OrderBy("m.uid " + orderBy)
+		q.Limit(filter.Limit + 1)
+	} else {
+		// name searches are ordered by relevance unless a created at sort is asked for
+		if !search.IsEmpty() && filter.CreatedAtSort == "" {
+			q.OrderBy(textsearch.Rank("m.name", "m.search_vector")+" DESC", search.RankArgs()...)
+		}
+		q.OrderBy("m.created_at " + orderBy)
+		q.Page(filter.Limit, filter.Offset)
+	}
 
-	rows, err := d.db.DB().QueryContext(ctx, q, params...)
+	query, args := q.Build()
+	rows, err := d.db.Executor(d.tx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return
 	}
+	defer rows.Close()
 
 	pagination = &response.Pagination{}
 	pagination.Limit = filter.Limit
-	pagination.Offset = filter.Offset
+	if filter.Cursor == nil {
+		pagination.Offset = filter.Offset
+	}
 
 	for rows.Next() {
 		m := Merchants{}
-		err = rows.Scan(&pagination.Total, &m.UID, &m.Name, &m.Category, &m.ImageURL, &m.Lat, &m.Lng, &m.CreatedAt)
+		err = rows.Scan(&pagination.Total, &m.ID, &m.UID, &m.Name, &m.Category, &m.ImageURL, &m.Lat, &m.Lng, &m.CreatedAt)
 		if err != nil {
 			return
 		}
 		merchants = append(merchants, m)
 	}
+	if filter.Cursor != nil && len(merchants) > filter.Limit {
+		merchants = merchants[:filter.Limit]
+		last := merchants[len(merchants)-1]
+		pagination.NextCursor = response.Cursor{CreatedAt: &last.CreatedAt, ID: last.UID}.Encode()
+	}
 	return
 }
 
+// ListByDistance implements Repository.
+// Merchants are filtered and paged before being joined with their items, so every
+// page holds up to limit merchants. A name matches the merchant or any of its
+// items, and only matching items are returned for merchants whose name does not.
 func (d *dbRepository) ListByDistance(ctx context.Context, filter ListMerchantsByDistancePayload) (merchantWithItem []MerchantsWithItem, pagination *response.Pagination, err error) {
 	merchantWithItem = make([]MerchantsWithItem, 0)
 
-	q := `
-		SELECT COUNT(*) OVER() AS total_count,
-		m.*,
+	search := textsearch.Parse(filter.Name)
+	nameMatch, nameMatchArgs := "TRUE", []interface{}{}
+	if !search.IsEmpty() {
+		nameMatch, nameMatchArgs = textsearch.Condition("name", "search_vector"), search.ConditionArgs()
+	}
+	// proximity is the straight line distance between the earth points, which sorts
+	// like distance but, unlike it, lets the GiST index return the nearest first
+	merchantsQuery := db.NewQuery(`
+		SELECT earth_distance(
+			ll_to_earth(location_lat, location_lng),
+			ll_to_earth(?, ?)
+		) AS distance,
+		ll_to_earth(location_lat, location_lng) <-> ll_to_earth(?, ?) AS proximity,
+		id, uid, name, merchant_category, image_url, location_lat, location_lng, created_at,
+		(`+nameMatch+`) AS name_match
+		FROM merchants
+	`, append([]interface{}{filter.Lat, filter.Lng, filter.Lat, filter.Lng}, nameMatchArgs...)...)
+	merchantsQuery.Where("deleted_at IS NULL")
+	if filter.MaxDistance > 0 {
+		// the box bounds the sphere of the radius, it only prefilters through the index
+		merchantsQuery.Where("earth_box(ll_to_earth(?, ?), ?) @> ll_to_earth(location_lat, location_lng)",
+			filter.Lat, filter.Lng, filter.MaxDistance)
+	}
+	if filter.MerchantUID != "" {
+		merchantsQuery.Where("uid = ?", filter.MerchantUID)
+	}
+	if filter.MerchantCategory != "" {
+		merchantsQuery.Where("merchant_category = ?", filter.MerchantCategory)
+	}
+	if filter.OpenNow {
+		merchantsQuery.Where(fmt.Sprintf(openNowConditionFormat, "merchants"))
+	}
+
+	pageQuery := merchantsQuery.Wrap("SELECT d.* FROM (%s) AS d")
+	if !search.IsEmpty() {
+		pageQuery.Where("d.name_match OR EXISTS (SELECT 1 FROM merchant_items i WHERE i.merchant_id = d.id AND i.deleted_at IS NULL AND ("+
+			textsearch.Condition("i.name", "i.search_vector")+"))", search.ConditionArgs()...)
+	}
+	if filter.MaxDistance > 0 {
+		pageQuery.Where("d.distance <= ?", filter.MaxDistance)
+	}
+	var countQuery *db.Query
+	if filter.WithTotal && filter.Cursor == nil {
+		// counting every match means finding them all rather than the nearest few,
+		// so only a request asking for the total pays for it
+		countQuery = pageQuery.Wrap("SELECT COUNT(*) FROM (%s) AS c")
+	}
+	if filter.Cursor != nil {
+		var after *response.Cursor
+		after, err = response.DecodeDistanceCursor(*filter.Cursor)
+		if err != nil {
+			return
+		}
+		if after != nil {
+			pageQuery.Where("(d.distance, d.uid) > (?, ?)", *after.Distance, after.ID)
+		}
+	}
+	pageQuery.OrderBy("d.proximity ASC").OrderBy("d.uid ASC")
+	if filter.Cursor != nil {
+		// one extra merchant tells whether there is a next page
+		pageQuery.Limit(filter.Limit + 1)
+	} else {
+		pageQuery.Page(filter.Limit, filter.Offset)
+	}
+
+	itemMatch, itemMatchArgs := "TRUE", []interface{}{}
+	if !search.IsEmpty() {
+		itemMatch, itemMatchArgs = textsearch.Condition("mi.name", "mi.search_vector"), search.ConditionArgs()
+	}
+	q := pageQuery.Wrap(`
+		SELECT m.distance, m.id, m.uid, m.name, m.merchant_category, m.image_url, m.location_lat, m.location_lng, m.created_at,
 		COALESCE(mi.uid, ''),
 		COALESCE(mi.name, ''),
 		COALESCE(mi.merchant_id, 0),
 		mi.item_category,
 		COALESCE(mi.price, 0),
 		COALESCE(mi.image_url, ''),
+		COALESCE(mi.is_available, false),
+		`+itemOptionGroupsColumn+`,
 		mi.created_at
-		FROM (
-			SELECT earth_distance(
-				ll_to_earth(location_lat, location_lng),
-				ll_to_earth($1, $2)
-			) as distance,
-			id, uid, name, merchant_category, image_url, location_lat, location_lng, created_at
-			FROM merchants
-			ORDER BY distance ASC
-			OFFSET $3 LIMIT $4
-		) AS m
-		LEFT JOIN merchant_items mi ON m.id = mi.merchant_id
-
-	`
-
-	paramNo := 5
-	params := make([]interface{}, 0)
-	params = append(params, filter.Lat)
-	params = append(params, filter.Lng)
-	params = append(params, filter.Offset)
-	params = append(params, filter.Limit)
-
-	if filter.MerchantUID != "" {
-		q += whereOrAnd(paramNo, 5)
-		q += fmt.Sprintf("m.uid = $%d ", paramNo)
-		paramNo += 1
-		params = append(params, filter.MerchantUID)
-	}
-	if filter.Name != "" {
-		q += whereOrAnd(paramNo, 5)
-		q += fmt.Sprintf("m.name ILIKE '%%%s%%' OR mi.name ILIKE '%%%s%%'", filter.Name, filter.Name)
-	}
-	if filter.MerchantCategory != "" {
-		q += whereOrAnd(paramNo, 5)
-		q += fmt.Sprintf("m.merchant_category = $%d ", paramNo)
-		params = append(params, filter.MerchantCategory)
-	}
+		FROM (%s) AS m
+		LEFT JOIN merchant_items mi ON m.id = mi.merchant_id AND mi.deleted_at IS NULL
+			AND (m.name_match OR `+itemMatch+`)
+	`, itemMatchArgs...)
+	q.OrderBy("m.distance ASC").OrderBy("m.uid ASC").OrderBy("mi.id ASC")
 
-	rows, err := d.db.DB().QueryContext(ctx, q, params...)
+	query, args := q.Build()
+	rows, err := d.db.Executor(d.tx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return
 	}
+	defer rows.Close()
 
 	pagination = &response.Pagination{}
 	pagination.Limit = filter.Limit
-	pagination.Offset = filter.Offset
+	if filter.Cursor == nil {
+		pagination.Offset = filter.Offset
+	}
 
 	for rows.Next() {
 		m := Merchants{}
 		mi := MerchantItems{}
 		var distance float64
-		err = rows.Scan(&pagination.Total, &distance, &m.ID, &m.UID, &m.Name, &m.Category, &m.ImageURL, &m.Lat, &m.Lng, &m.CreatedAt,
-			&mi.UID, &mi.Name, &mi.MerchantID, &mi.Category, &mi.Price, &mi.ImageURL, &mi.CreatedAt)
+		err = rows.Scan(&distance, &m.ID, &m.UID, &m.Name, &m.Category, &m.ImageURL, &m.Lat, &m.Lng, &m.CreatedAt,
+			&mi.UID, &mi.Name, &mi.MerchantID, &mi.Category, &mi.Price, &mi.ImageURL, &mi.IsAvailable, &mi.OptionGroups, &mi.CreatedAt)
 		if err != nil {
 			return
 		}
@@ -209,23 +297,34 @@ func (d *dbRepository) ListByDistance(ctx context.Context, filter ListMerchantsB
 			ImageURL:  m.ImageURL,
 			Lat:       m.Lat,
 			Lng:       m.Lng,
+			Distance:  distance,
 			CreatedAt: m.CreatedAt,
 			Item:      mi,
 		})
 	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+	if filter.Cursor != nil {
+		merchantWithItem, pagination.NextCursor = nextDistancePage(merchantWithItem, filter.Limit)
+	}
+	if countQuery != nil {
+		query, args = countQuery.Build()
+		err = d.db.Executor(d.tx).QueryRowContext(ctx, query, args...).Scan(&pagination.Total)
+	}
 	return
 }
 
 func (d *dbRepository) GetByUID(ctx context.Context, uid string) (merchant *Merchants, err error) {
 	getMerchantQuery := `
-		SELECT id, uid, name, merchant_category, image_url, location_lat, location_lng, created_at
+		SELECT id, uid, name, merchant_category, image_url, location_lat, location_lng, timezone, created_at
 		FROM merchants
-		WHERE uid = $1
+		WHERE uid = $1 AND deleted_at IS NULL
 	`
 
-	row := d.db.DB().QueryRowContext(ctx, getMerchantQuery, uid)
+	row := d.db.Executor(d.tx).QueryRowContext(ctx, getMerchantQuery, uid)
 	m := Merchants{}
-	err = row.Scan(&m.ID, &m.UID, &m.Name, &m.Category, &m.ImageURL, &m.Lat, &m.Lng, &m.CreatedAt)
+	err = row.Scan(&m.ID, &m.UID, &m.Name, &m.Category, &m.ImageURL, &m.Lat, &m.Lng, &m.Timezone, &m.CreatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			err = ErrMerchantNotFound
@@ -238,9 +337,458 @@ func (d *dbRepository) GetByUID(ctx context.Context, uid string) (merchant *Merc
 	return
 }
 
-func whereOrAnd(paramNo int, targetParamNo int) string {
-	if paramNo == targetParamNo {
-		return "WHERE "
+// Update implements Repository.
+func (d *dbRepository) Update(ctx context.Context, merchant *Merchants) (err error) {
+	q := `
+		UPDATE merchants
+		SET name = $1, merchant_category = $2, image_url = $3, location_lat = $4, location_lng = $5
+		WHERE uid = $6 AND deleted_at IS NULL;
+	`
+	res, err := d.db.Executor(d.tx).ExecContext(ctx, q, merchant.Name, merchant.Category, merchant.ImageURL, merchant.Lat, merchant.Lng, merchant.UID)
+	if err != nil {
+		return
+	}
+	return expectOneRow(res)
+}
+
+// Delete implements Repository.
+// Merchants are only soft deleted so that historical orders keep resolving them.
+func (d *dbRepository) Delete(ctx context.Context, uid string) (err error) {
+	q := `
+		UPDATE merchants
+		SET deleted_at = current_timestamp
+		WHERE uid = $1 AND deleted_at IS NULL;
+	`
+	res, err := d.db.Executor(d.tx).ExecContext(ctx, q, uid)
+	if err != nil {
+		return
+	}
+	return expectOneRow(res)
+}
+
+// Restore implements Repository.
+func (d *dbRepository) Restore(ctx context.Context, uid string) (err error) {
+	q := `
+		UPDATE merchants
+		SET deleted_at = NULL
+		WHERE uid = $1 AND deleted_at IS NOT NULL;
+	`
+	res, err := d.db.Executor(d.tx).ExecContext(ctx, q, uid)
+	if err != nil {
+		return
+	}
+	return expectOneRow(res)
+}
+
+// IsMember implements Repository.
+func (d *dbRepository) IsMember(ctx context.Context, merchantID uint64, userUID string) (member bool, err error) {
+	q := `
+		SELECT EXISTS (
+			SELECT 1 FROM merchant_members mm
+			JOIN users u ON u.id = mm.user_id
+			WHERE mm.merchant_id = $1 AND u.uid = $2
+		);
+	`
+	err = d.db.Executor(d.tx).QueryRowContext(ctx, q, merchantID, userUID).Scan(&member)
+	return
+}
+
+// AddMember implements Repository.
+// Adding a member twice is not an error.
+func (d *dbRepository) AddMember(ctx context.Context, merchantID uint64, userUID string) (err error) {
+	q := `
+		INSERT INTO merchant_members (merchant_id, user_id)
+		SELECT $1, id FROM users WHERE uid = $2
+		ON CONFLICT DO NOTHING
+		RETURNING user_id;
+	`
+	var userID uint64
+	err = d.db.Executor(d.tx).QueryRowContext(ctx, q, merchantID, userUID).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// either the user does not exist or they already are a member
+		var exists bool
+		err = d.db.Executor(d.tx).QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE uid = $1);", userUID).Scan(&exists)
+		if err == nil && !exists {
+			err = ErrUserNotFound
+		}
+	}
+	return
+}
+
+// RemoveMember implements Repository.
+func (d *dbRepository) RemoveMember(ctx context.Context, merchantID uint64, userUID string) (err error) {
+	q := `
+		DELETE FROM merchant_members mm
+		USING users u
+		WHERE u.id = mm.user_id AND mm.merchant_id = $1 AND u.uid = $2;
+	`
+	res, err := d.db.Executor(d.tx).ExecContext(ctx, q, merchantID, userUID)
+	if err != nil {
+		return
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if rowsAffected == 0 {
+		return ErrMemberNotFound
+	}
+	return
+}
+
+// ListMembers implements Repository.
+func (d *dbRepository) ListMembers(ctx context.Context, merchantID uint64) (members []Member, err error) {
+	q := `
+		SELECT u.uid, u.username, u.email, mm.created_at
+		FROM merchant_members mm
+		JOIN users u ON u.id = mm.user_id
+		WHERE mm.merchant_id = $1
+		ORDER BY mm.created_at, u.uid;
+	`
+	rows, err := d.db.Executor(d.tx).QueryContext(ctx, q, merchantID)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	members = make([]Member, 0)
+	for rows.Next() {
+		m := Member{}
+		if err = rows.Scan(&m.UserUID, &m.Username, &m.Email, &m.CreatedAt); err != nil {
+			return
+		}
+		members = append(members, m)
+	}
+	err = rows.Err()
+	return
+}
+
+// ListByMember implements Repository.
+func (d *dbRepository) ListByMember(ctx context.Context, userUID string) (merchants []Merchants, err error) {
+	q := `
+		SELECT m.id, m.uid, m.name, m.merchant_category, m.image_url, m.location_lat, m.location_lng, m.timezone, m.created_at
+		FROM merchants m
+		JOIN merchant_members mm ON mm.merchant_id = m.id
+		JOIN users u ON u.id = mm.user_id
+		WHERE u.uid = $1 AND m.deleted_at IS NULL
+		ORDER BY m.created_at DESC, m.uid;
+	`
+	rows, err := d.db.Executor(d.tx).QueryContext(ctx, q, userUID)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	merchants = make([]Merchants, 0)
+	for rows.Next() {
+		m := Merchants{}
+		err = rows.Scan(&m.ID, &m.UID, &m.Name, &m.Category, &m.ImageURL, &m.Lat, &m.Lng, &m.Timezone, &m.CreatedAt)
+		if err != nil {
+			return
+		}
+		merchants = append(merchants, m)
+	}
+	err = rows.Err()
+	return
+}
+
+// itemOptionGroupsColumn aggregates the option groups of the merchant item mi
+// as JSON, to be scanned into ItemOptionGroups.
+const itemOptionGroupsColumn = `(
+	SELECT jsonb_agg(jsonb_build_object(
+		'optionGroupId', g.uid,
+		'name', g.name,
+		'minSelections', g.min_selections,
+		'maxSelections', g.max_selections,
+		'options', (
+			SELECT jsonb_agg(jsonb_build_object('optionId', o.uid, 'name', o.name, 'priceDelta', o.price_delta) ORDER BY o.id)
+			FROM item_options o
+			WHERE o.option_group_id = g.id
+		)
+	) ORDER BY g.id)
+	FROM item_option_groups g
+	WHERE g.item_id = mi.id
+)`
+
+// ListAll implements Repository.
+func (d *dbRepository) ListAll(ctx context.Context) (merchants []Merchants, err error) {
+	q := `
+		SELECT id, uid, name, merchant_category, image_url, location_lat, location_lng, timezone, created_at
+		FROM merchants
+		WHERE deleted_at IS NULL
+		ORDER BY id
+	`
+	rows, err := d.db.Executor(d.tx).QueryContext(ctx, q)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	merchants = make([]Merchants, 0)
+	for rows.Next() {
+		m := Merchants{}
+		err = rows.Scan(&m.ID, &m.UID, &m.Name, &m.Category, &m.ImageURL, &m.Lat, &m.Lng, &m.Timezone, &m.CreatedAt)
+		if err != nil {
+			return
+		}
+		merchants = append(merchants, m)
+	}
+	err = rows.Err()
+	return
+}
+
+// ListItemsByMerchantIDs implements Repository.
+func (d *dbRepository) ListItemsByMerchantIDs(ctx context.Context, merchantIDs []uint64) (items map[uint64][]MerchantItems, err error) {
+	items = make(map[uint64][]MerchantItems)
+	if len(merchantIDs) == 0 {
+		return
+	}
+	ids := make([]int64, len(merchantIDs))
+	for i, id := range merchantIDs {
+		ids[i] = int64(id)
+	}
+
+	q := db.NewQuery(`
+		SELECT mi.uid, mi.name, mi.merchant_id, mi.item_category, mi.price, mi.image_url, mi.is_available,
+		` + itemOptionGroupsColumn + `,
+		mi.created_at
+		FROM merchant_items mi
+	`)
+	q.WhereAny("mi.merchant_id", ids).Where("mi.deleted_at IS NULL")
+	q.OrderBy("mi.id ASC")
+
+	query, args := q.Build()
+	rows, err := d.db.Executor(d.tx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		mi := MerchantItems{}
+		err = rows.Scan(&mi.UID, &mi.Name, &mi.MerchantID, &mi.Category, &mi.Price, &mi.ImageURL, &mi.IsAvailable, &mi.OptionGroups, &mi.CreatedAt)
+		if err != nil {
+			return
+		}
+		items[mi.MerchantID] = append(items[mi.MerchantID], mi)
+	}
+	err = rows.Err()
+	return
+}
+
+// openNowConditionFormat matches merchants open at the current time in their own
+// time zone, following the same rules as Schedule.IsOpen. Format it with the
+// merchants table alias.
+const openNowConditionFormat = `(
+	NOT EXISTS (
+		SELECT 1 FROM merchant_closures c
+		WHERE c.merchant_id = %[1]s.id AND c.date = (current_timestamp AT TIME ZONE %[1]s.timezone)::date
+	) AND (
+		NOT EXISTS (SELECT 1 FROM merchant_opening_hours h WHERE h.merchant_id = %[1]s.id)
+		OR EXISTS (
+			SELECT 1 FROM merchant_opening_hours h,
+			LATERAL (
+				SELECT EXTRACT(DOW FROM lt)::int AS dow, (EXTRACT(HOUR FROM lt) * 60 + EXTRACT(MINUTE FROM lt))::int AS minute
+				FROM (SELECT current_timestamp AT TIME ZONE %[1]s.timezone AS lt) l
+			) n
+			WHERE h.merchant_id = %[1]s.id AND (
+				(h.day_of_week = n.dow AND h.opens_minute <= n.minute AND (n.minute < h.closes_minute OR h.closes_minute <= h.opens_minute))
+				OR (h.day_of_week = (n.dow + 6) %% 7 AND h.closes_minute <= h.opens_minute AND n.minute < h.closes_minute)
+			)
+		)
+	)
+)`
+
+// ListSchedules implements Repository.
+// Closures before yesterday are left out as they cannot affect the current schedule.
+func (d *dbRepository) ListSchedules(ctx context.Context, merchantIDs []uint64) (schedules map[uint64]*Schedule, err error) {
+	schedules = make(map[uint64]*Schedule)
+	if len(merchantIDs) == 0 {
+		return
+	}
+	ids := make([]int64, len(merchantIDs))
+	for i, id := range merchantIDs {
+		ids[i] = int64(id)
+	}
+
+	q := `
+		SELECT id, timezone
+		FROM merchants
+		WHERE id = ANY($1);
+	`
+	rows, err := d.db.Executor(d.tx).QueryContext(ctx, q, ids)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			id       uint64
+			timezone string
+		)
+		err = rows.Scan(&id, &timezone)
+		if err != nil {
+			return
+		}
+		location, loadErr := time.LoadLocation(timezone)
+		if loadErr != nil {
+			location = time.UTC
+		}
+		schedules[id] = &Schedule{
+			Location:     location,
+			OpeningHours: make([]OpeningHours, 0),
+			Closures:     make([]Closure, 0),
+		}
+	}
+
+	q = `
+		SELECT merchant_id, day_of_week, opens_minute, closes_minute
+		FROM merchant_opening_hours
+		WHERE merchant_id = ANY($1)
+		ORDER BY merchant_id, day_of_week, opens_minute;
+	`
+	hourRows, err := d.db.Executor(d.tx).QueryContext(ctx, q, ids)
+	if err != nil {
+		return
+	}
+	defer hourRows.Close()
+	for hourRows.Next() {
+		var (
+			merchantID uint64
+			h          OpeningHours
+		)
+		err = hourRows.Scan(&merchantID, &h.DayOfWeek, &h.OpensMinute, &h.ClosesMinute)
+		if err != nil {
+			return
+		}
+		if schedule, ok := schedules[merchantID]; ok {
+			schedule.OpeningHours = append(schedule.OpeningHours, h)
+		}
+	}
+
+	q = `
+		SELECT merchant_id, date, reason
+		FROM merchant_closures
+		WHERE merchant_id = ANY($1) AND date >= current_date - 1
+		ORDER BY merchant_id, date;
+	`
+	closureRows, err := d.db.Executor(d.tx).QueryContext(ctx, q, ids)
+	if err != nil {
+		return
+	}
+	defer closureRows.Close()
+	for closureRows.Next() {
+		var (
+			merchantID uint64
+			c          Closure
+		)
+		err = closureRows.Scan(&merchantID, &c.Date, &c.Reason)
+		if err != nil {
+			return
+		}
+		if schedule, ok := schedules[merchantID]; ok {
+			schedule.Closures = append(schedule.Closures, c)
+		}
+	}
+	return
+}
+
+// ReplaceOpeningHours implements Repository.
+// Run it in a transaction so the weekly schedule is never seen half replaced.
+func (d *dbRepository) ReplaceOpeningHours(ctx context.Context, merchantID uint64, timezone string, openingHours []OpeningHours) (err error) {
+	q := `
+		UPDATE merchants
+		SET timezone = $1
+		WHERE id = $2;
+	`
+	_, err = d.db.Executor(d.tx).ExecContext(ctx, q, timezone, merchantID)
+	if err != nil {
+		return
+	}
+	q = `
+		DELETE FROM merchant_opening_hours
+		WHERE merchant_id = $1;
+	`
+	_, err = d.db.Executor(d.tx).ExecContext(ctx, q, merchantID)
+	if err != nil {
+		return
+	}
+	q = `
+		INSERT INTO merchant_opening_hours (
+			merchant_id, day_of_week, opens_minute, closes_minute
+		) VALUES (
+			$1, $2, $3, $4
+		);
+	`
+	for _, h := range openingHours {
+		_, err = d.db.Executor(d.tx).ExecContext(ctx, q, merchantID, h.DayOfWeek, h.OpensMinute, h.ClosesMinute)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// InsertClosure implements Repository.
+func (d *dbRepository) InsertClosure(ctx context.Context, merchantID uint64, closure Closure) (err error) {
+	q := `
+		INSERT INTO merchant_closures (
+			merchant_id, date, reason
+		) VALUES (
+			$1, $2, $3
+		);
+	`
+	_, err = d.db.Executor(d.tx).ExecContext(ctx, q, merchantID, closure.Date, closure.Reason)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrClosureExists
+	}
+	return
+}
+
+// DeleteClosure implements Repository.
+func (d *dbRepository) DeleteClosure(ctx context.Context, merchantID uint64, date time.Time) (err error) {
+	q := `
+		DELETE FROM merchant_closures
+		WHERE merchant_id = $1 AND date = $2;
+	`
+	res, err := d.db.Executor(d.tx).ExecContext(ctx, q, merchantID, date)
+	if err != nil {
+		return
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if rowsAffected == 0 {
+		return ErrClosureNotFound
+	}
+	return
+}
+
+func expectOneRow(res sql.Result) error {
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrMerchantNotFound
+	}
+	return nil
+}
+
+// nextDistancePage drops the rows of the merchant past limit, which was only
+// fetched to tell whether there is a next page, and returns the cursor to it.
+func nextDistancePage(rows []MerchantsWithItem, limit int) ([]MerchantsWithItem, string) {
+	merchantCount := 0
+	for i, row := range rows {
+		if i == 0 || row.ID != rows[i-1].ID {
+			merchantCount += 1
+		}
+		if merchantCount > limit {
+			last := rows[i-1]
+			return rows[:i], response.Cursor{Distance: &last.Distance, ID: last.UID}.Encode()
+		}
 	}
-	return "AND "
+	return rows, ""
 }
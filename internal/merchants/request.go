@@ -1,6 +1,9 @@
 package merchants
 
 import (
+	"errors"
+	"time"
+
 	validations "github.com/citadel-corp/belimang/internal/common/validation"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/go-ozzo/ozzo-validation/v4/is"
@@ -50,6 +53,13 @@ func (p Location) Validate() error {
 	return nil
 }
 
+type UpdateMerchantPayload struct {
+	Name     *string           `json:"name"`
+	Category *MerchantCategory `json:"merchantCategory"`
+	ImageURL *string           `json:"imageUrl"`
+	Location *Location         `json:"location"`
+}
+
 type ListMerchantsPayload struct {
 	MerchantUID      string           `schema:"merchantId" binding:"omitempty"`
 	Name             string           `schema:"name" binding:"omitempty"`
@@ -57,6 +67,7 @@ type ListMerchantsPayload struct {
 	CreatedAtSort    string           `schema:"createdAt" binding:"omitempty"`
 	Limit            int              `schema:"limit" binding:"omitempty"`
 	Offset           int              `schema:"offset" binding:"omitempty"`
+	Cursor           *string          `schema:"cursor" binding:"omitempty"` // set, even empty, to page by cursor
 }
 
 func (p ListMerchantsPayload) Validate() error {
@@ -72,10 +83,14 @@ type ListMerchantsByDistancePayload struct {
 	MerchantUID      string           `schema:"merchantId" binding:"omitempty"`
 	Name             string           `schema:"name" binding:"omitempty"`
 	MerchantCategory MerchantCategory `schema:"merchantCategory"`
+	OpenNow          bool             `schema:"openNow" binding:"omitempty"`
+	MaxDistance      float64          `schema:"maxDistance" binding:"omitempty"` // meters, no limit when zero
 	Lat              string
 	Lng              string
-	Limit            int `schema:"limit" binding:"omitempty"`
-	Offset           int `schema:"offset" binding:"omitempty"`
+	Limit            int     `schema:"limit" binding:"omitempty"`
+	Offset           int     `schema:"offset" binding:"omitempty"`
+	Cursor           *string `schema:"cursor" binding:"omitempty"`    // set, even empty, to page by cursor
+	WithTotal        bool    `schema:"withTotal" binding:"omitempty"` // count every match, offset pages only
 }
 
 func (p ListMerchantsByDistancePayload) Validate() error {
@@ -83,7 +98,69 @@ func (p ListMerchantsByDistancePayload) Validate() error {
 		validation.Field(&p.MerchantUID),
 		validation.Field(&p.Name),
 		validation.Field(&p.MerchantCategory, validation.In(MerchantCategories...)),
+		validation.Field(&p.MaxDistance, validation.Min(0.0)),
 		validation.Field(&p.Lat, validation.Required, is.Latitude),
 		validation.Field(&p.Lng, validation.Required, is.Longitude),
 	)
 }
+
+type OpeningHoursPayload struct {
+	DayOfWeek *int   `json:"dayOfWeek"`
+	OpensAt   string `json:"opensAt"`
+	ClosesAt  string `json:"closesAt"`
+}
+
+func (p OpeningHoursPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.DayOfWeek, validation.NotNil, validation.In(DaysOfWeek...)),
+		validation.Field(&p.OpensAt, validation.Required, validation.By(minuteRule)),
+		validation.Field(&p.ClosesAt, validation.Required, validation.By(minuteRule)),
+	)
+}
+
+type UpdateOpeningHoursPayload struct {
+	Timezone     string                `json:"timezone"`
+	OpeningHours []OpeningHoursPayload `json:"openingHours"`
+}
+
+func (p UpdateOpeningHoursPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Timezone, validation.Required, validation.By(timezoneRule)),
+		validation.Field(&p.OpeningHours),
+	)
+}
+
+type CreateClosurePayload struct {
+	Date   string `json:"date"`
+	Reason string `json:"reason"`
+}
+
+func (p CreateClosurePayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Date, validation.Required, validation.Date(DateFormat)),
+		validation.Field(&p.Reason, validation.Length(0, MaxClosureReason)),
+	)
+}
+
+func minuteRule(value interface{}) error {
+	_, err := ParseMinute(value.(string))
+	return err
+}
+
+func timezoneRule(value interface{}) error {
+	_, err := time.LoadLocation(value.(string))
+	if err != nil {
+		return errors.New("timezone is not valid")
+	}
+	return nil
+}
+
+type AddMemberPayload struct {
+	UserID string `json:"userId"`
+}
+
+func (p AddMemberPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.UserID, validation.Required),
+	)
+}
@@ -20,45 +20,69 @@ type MerchantsResponse struct {
 	Category  string           `json:"merchantCategory"`
 	ImageURL  string           `json:"imageUrl"`
 	Location  LocationResponse `json:"location"`
+	IsOpen    *bool            `json:"isOpen,omitempty"`
+	OpensAt   *time.Time       `json:"opensAt,omitempty"`
 	CreatedAt int              `json:"createdAt"`
 }
 
-func CreateMerchantsResponse(merchants []Merchants) []MerchantsResponse {
+// setOpenStatus fills whether the merchant is open at now and, if closed, when it opens next.
+func (r *MerchantsResponse) setOpenStatus(schedule *Schedule, now time.Time) {
+	if schedule == nil {
+		return
+	}
+	isOpen := schedule.IsOpen(now)
+	r.IsOpen = &isOpen
+	r.OpensAt = schedule.NextOpening(now)
+}
+
+func CreateMerchantsResponse(merchants []Merchants, schedules map[uint64]*Schedule, now time.Time) []MerchantsResponse {
 	merchantsResponse := make([]MerchantsResponse, 0)
 	for _, m := range merchants {
-		merchantsResponse = append(merchantsResponse, MerchantsResponse{
+		merchantResponse := MerchantsResponse{
 			UID:       m.UID,
 			Name:      m.Name,
 			Category:  string(m.Category),
 			ImageURL:  m.ImageURL,
 			Location:  LocationResponse{Lat: m.Lat, Lng: m.Lng},
 			CreatedAt: m.CreatedAt.Nanosecond(),
-		})
+		}
+		merchantResponse.setOpenStatus(schedules[m.ID], now)
+		merchantsResponse = append(merchantsResponse, merchantResponse)
 	}
 
 	return merchantsResponse
 }
 
 type MerchantItemResponse struct {
-	UID             string `json:"itemId"`
-	Name            string `json:"name"`
-	ProductCategory string `json:"productCategory"`
-	Price           int    `json:"price"`
-	ImageURL        string `json:"imageUrl"`
-	CreatedAt       int    `json:"createdAt"`
+	UID             string                    `json:"itemId"`
+	Name            string                    `json:"name"`
+	ProductCategory string                    `json:"productCategory"`
+	Price           int                       `json:"price"`
+	ImageURL        string                    `json:"imageUrl"`
+	IsAvailable     bool                      `json:"isAvailable"`
+	OptionGroups    []ItemOptionGroupResponse `json:"optionGroups"`
+	CreatedAt       int                       `json:"createdAt"`
 }
 
 type MerchantWithItemsResponse struct {
-	Merchant MerchantsResponse      `json:"merchant"`
-	Items    []MerchantItemResponse `json:"items"`
+	Merchant                       MerchantsResponse      `json:"merchant"`
+	Items                          []MerchantItemResponse `json:"items"`
+	Distance                       float64                `json:"distance"` // meters
+	EstimatedDeliveryTimeInMinutes int                    `json:"estimatedDeliveryTimeInMinutes"`
 }
 
-func CreateMerchantsWithItemsResponse(merchants []MerchantsWithItem) []MerchantWithItemsResponse {
-	merchantMap := make(map[uint64]*MerchantWithItemsResponse)
+// CreateMerchantsWithItemsResponse groups the items by merchant, keeping the order
+// the merchants come in.
+func CreateMerchantsWithItemsResponse(merchants []MerchantsWithItem, schedules map[uint64]*Schedule, now time.Time) []MerchantWithItemsResponse {
+	result := make([]MerchantWithItemsResponse, 0)
+	merchantMap := make(map[uint64]int) // key: merchant id, value: index in result
 
 	for _, merchant := range merchants {
-		if _, exists := merchantMap[merchant.ID]; !exists {
-			merchantMap[merchant.ID] = &MerchantWithItemsResponse{
+		i, exists := merchantMap[merchant.ID]
+		if !exists {
+			i = len(result)
+			merchantMap[merchant.ID] = i
+			result = append(result, MerchantWithItemsResponse{
 				Merchant: MerchantsResponse{
 					UID:      merchant.UID,
 					Name:     merchant.Name,
@@ -70,29 +94,29 @@ func CreateMerchantsWithItemsResponse(merchants []MerchantsWithItem) []MerchantW
 					},
 					CreatedAt: merchant.CreatedAt.Nanosecond(),
 				},
-				Items: []MerchantItemResponse{},
-			}
+				Items:                          []MerchantItemResponse{},
+				Distance:                       merchant.Distance,
+				EstimatedDeliveryTimeInMinutes: merchant.DeliveryMinutes,
+			})
+			result[i].Merchant.setOpenStatus(schedules[merchant.ID], now)
 		}
 
 		if merchant.Item.UID == "" {
 			continue
 		}
 
-		merchantMap[merchant.ID].Items = append(merchantMap[merchant.ID].Items, MerchantItemResponse{
+		result[i].Items = append(result[i].Items, MerchantItemResponse{
 			UID:             merchant.Item.UID,
 			Name:            merchant.Item.Name,
 			ProductCategory: getString(merchant.Item.Category),
 			Price:           merchant.Item.Price,
 			ImageURL:        merchant.Item.ImageURL,
+			IsAvailable:     merchant.Item.IsAvailable,
+			OptionGroups:    merchant.Item.OptionGroups,
 			CreatedAt:       getTime(merchant.Item.CreatedAt).Nanosecond(),
 		})
 	}
 
-	var result []MerchantWithItemsResponse
-	for _, merchant := range merchantMap {
-		result = append(result, *merchant)
-	}
-
 	return result
 }
 
@@ -109,3 +133,66 @@ func getTime(ns sql.NullTime) time.Time {
 	}
 	return time.Time{}
 }
+
+type OpeningHoursResponse struct {
+	DayOfWeek int    `json:"dayOfWeek"`
+	OpensAt   string `json:"opensAt"`
+	ClosesAt  string `json:"closesAt"`
+}
+
+type ClosureResponse struct {
+	Date   string `json:"date"`
+	Reason string `json:"reason"`
+}
+
+type ScheduleResponse struct {
+	Timezone     string                 `json:"timezone"`
+	OpeningHours []OpeningHoursResponse `json:"openingHours"`
+	Closures     []ClosureResponse      `json:"closures"`
+	IsOpen       bool                   `json:"isOpen"`
+	OpensAt      *time.Time             `json:"opensAt,omitempty"`
+}
+
+func CreateScheduleResponse(schedule *Schedule, now time.Time) *ScheduleResponse {
+	res := &ScheduleResponse{
+		Timezone:     schedule.Location.String(),
+		OpeningHours: make([]OpeningHoursResponse, 0, len(schedule.OpeningHours)),
+		Closures:     make([]ClosureResponse, 0, len(schedule.Closures)),
+		IsOpen:       schedule.IsOpen(now),
+		OpensAt:      schedule.NextOpening(now),
+	}
+	for _, h := range schedule.OpeningHours {
+		res.OpeningHours = append(res.OpeningHours, OpeningHoursResponse{
+			DayOfWeek: int(h.DayOfWeek),
+			OpensAt:   FormatMinute(h.OpensMinute),
+			ClosesAt:  FormatMinute(h.ClosesMinute),
+		})
+	}
+	for _, c := range schedule.Closures {
+		res.Closures = append(res.Closures, ClosureResponse{
+			Date:   c.Date.Format(DateFormat),
+			Reason: c.Reason,
+		})
+	}
+	return res
+}
+
+type MemberResponse struct {
+	UserID    string    `json:"userId"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func CreateMembersResponse(members []Member) []MemberResponse {
+	resp := make([]MemberResponse, 0, len(members))
+	for _, m := range members {
+		resp = append(resp, MemberResponse{
+			UserID:    m.UserUID,
+			Username:  m.Username,
+			Email:     m.Email,
+			CreatedAt: m.CreatedAt,
+		})
+	}
+	return resp
+}
@@ -0,0 +1,115 @@
+package merchants
+
+import (
+	"fmt"
+	"time"
+)
+
+// ScheduleLookahead is how far ahead the next opening of a closed merchant is searched.
+var ScheduleLookahead = 14
+
+// OpeningHours is a weekly opening window in the merchant time zone. A window
+// closing at or before its opening minute ends on the next day.
+type OpeningHours struct {
+	DayOfWeek    time.Weekday
+	OpensMinute  int
+	ClosesMinute int
+}
+
+func (h OpeningHours) overnight() bool {
+	return h.ClosesMinute <= h.OpensMinute
+}
+
+// Closure closes the merchant for a whole local date, e.g. a holiday.
+type Closure struct {
+	Date   time.Time
+	Reason string
+}
+
+// Schedule tells when a merchant is open. Merchants without opening hours are
+// always open unless closed for the date.
+type Schedule struct {
+	Location     *time.Location
+	OpeningHours []OpeningHours
+	Closures     []Closure
+}
+
+func (s *Schedule) closedOn(local time.Time) bool {
+	y, m, d := local.Date()
+	for _, closure := range s.Closures {
+		cy, cm, cd := closure.Date.Date()
+		if y == cy && m == cm && d == cd {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOpen reports whether the merchant is open at t.
+func (s *Schedule) IsOpen(t time.Time) bool {
+	local := t.In(s.Location)
+	if s.closedOn(local) {
+		return false
+	}
+	if len(s.OpeningHours) == 0 {
+		return true
+	}
+	minute := local.Hour()*60 + local.Minute()
+	weekday := local.Weekday()
+	yesterday := (weekday + 6) % 7
+	for _, h := range s.OpeningHours {
+		if h.DayOfWeek == weekday && h.OpensMinute <= minute && (minute < h.ClosesMinute || h.overnight()) {
+			return true
+		}
+		if h.DayOfWeek == yesterday && h.overnight() && minute < h.ClosesMinute {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpening returns when the merchant opens next after t, or nil when it is open
+// at t or does not open within ScheduleLookahead days.
+func (s *Schedule) NextOpening(t time.Time) *time.Time {
+	if s.IsOpen(t) {
+		return nil
+	}
+	local := t.In(s.Location)
+	for offset := 0; offset <= ScheduleLookahead; offset++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+offset, 0, 0, 0, 0, s.Location)
+		if s.closedOn(day) {
+			continue
+		}
+		if len(s.OpeningHours) == 0 {
+			return &day
+		}
+		var next *time.Time
+		for _, h := range s.OpeningHours {
+			if h.DayOfWeek != day.Weekday() {
+				continue
+			}
+			opensAt := time.Date(day.Year(), day.Month(), day.Day(), 0, h.OpensMinute, 0, 0, s.Location)
+			if opensAt.After(t) && (next == nil || opensAt.Before(*next)) {
+				next = &opensAt
+			}
+		}
+		if next != nil {
+			return next
+		}
+	}
+	return nil
+}
+
+// FormatMinute formats minutes since midnight as HH:MM.
+func FormatMinute(minute int) string {
+	return fmt.Sprintf("%02d:%02d", minute/60, minute%60)
+}
+
+// ParseMinute parses HH:MM into minutes since midnight.
+func ParseMinute(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("time %q must be formatted as HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -2,8 +2,14 @@ package merchants
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
+	"time"
 
+	"github.com/citadel-corp/belimang/internal/common/db"
 	"github.com/citadel-corp/belimang/internal/common/id"
+	"github.com/citadel-corp/belimang/internal/common/middleware"
+	"github.com/citadel-corp/belimang/internal/common/permission"
 	"github.com/citadel-corp/belimang/internal/common/response"
 )
 
@@ -11,14 +17,33 @@ type Service interface {
 	Create(ctx context.Context, req CreateMerchantPayload) (*MerchantUIDResponse, error)
 	List(ctx context.Context, req ListMerchantsPayload) ([]MerchantsResponse, *response.Pagination, error)
 	ListByDistance(ctx context.Context, req ListMerchantsByDistancePayload) ([]MerchantWithItemsResponse, *response.Pagination, error)
+	Update(ctx context.Context, uid string, req UpdateMerchantPayload) (*MerchantsResponse, error)
+	Delete(ctx context.Context, uid string) error
+	Restore(ctx context.Context, uid string) error
+	GetSchedule(ctx context.Context, uid string) (*ScheduleResponse, error)
+	UpdateOpeningHours(ctx context.Context, uid string, req UpdateOpeningHoursPayload) (*ScheduleResponse, error)
+	CreateClosure(ctx context.Context, uid string, req CreateClosurePayload) (*ScheduleResponse, error)
+	DeleteClosure(ctx context.Context, uid string, date string) error
+	ListMine(ctx context.Context) ([]MerchantsResponse, error)
+	ListMembers(ctx context.Context, uid string) ([]MemberResponse, error)
+	AddMember(ctx context.Context, uid string, req AddMemberPayload) ([]MemberResponse, error)
+	RemoveMember(ctx context.Context, uid string, userUID string) error
+}
+
+// DeliveryEstimator estimates how many minutes an order from a merchant of the
+// category takes to arrive distance meters away for a courier leaving at departure.
+type DeliveryEstimator interface {
+	EstimateMinutes(category MerchantCategory, distance float64, departure time.Time) int
 }
 
 type merchantService struct {
+	transactor db.Transactor
 	repository Repository
+	estimator  DeliveryEstimator
 }
 
-func NewService(repository Repository) Service {
-	return &merchantService{repository: repository}
+func NewService(transactor db.Transactor, repository Repository, estimator DeliveryEstimator) Service {
+	return &merchantService{transactor: transactor, repository: repository, estimator: estimator}
 }
 
 func (s *merchantService) Create(ctx context.Context, req CreateMerchantPayload) (*MerchantUIDResponse, error) {
@@ -49,8 +74,16 @@ func (s *merchantService) List(ctx context.Context, req ListMerchantsPayload) ([
 	if err != nil {
 		return []MerchantsResponse{}, nil, err
 	}
+	merchantIDs := make([]uint64, 0, len(merchants))
+	for _, merchant := range merchants {
+		merchantIDs = append(merchantIDs, merchant.ID)
+	}
+	schedules, err := s.repository.ListSchedules(ctx, merchantIDs)
+	if err != nil {
+		return []MerchantsResponse{}, nil, err
+	}
 
-	return CreateMerchantsResponse(merchants), pagination, nil
+	return CreateMerchantsResponse(merchants, schedules, time.Now()), pagination, nil
 }
 
 func (s *merchantService) ListByDistance(ctx context.Context, req ListMerchantsByDistancePayload) ([]MerchantWithItemsResponse, *response.Pagination, error) {
@@ -62,6 +95,247 @@ func (s *merchantService) ListByDistance(ctx context.Context, req ListMerchantsB
 	if err != nil {
 		return []MerchantWithItemsResponse{}, nil, err
 	}
+	merchantIDs := make([]uint64, 0, len(merchantsWithItem))
+	for _, merchant := range merchantsWithItem {
+		merchantIDs = append(merchantIDs, merchant.ID)
+	}
+	schedules, err := s.repository.ListSchedules(ctx, merchantIDs)
+	if err != nil {
+		return []MerchantWithItemsResponse{}, nil, err
+	}
+	now := time.Now()
+	for i, merchant := range merchantsWithItem {
+		merchantsWithItem[i].DeliveryMinutes = s.estimator.EstimateMinutes(merchant.Category, merchant.Distance, now)
+	}
+
+	return CreateMerchantsWithItemsResponse(merchantsWithItem, schedules, now), pagination, nil
+}
+
+func (s *merchantService) Update(ctx context.Context, uid string, req UpdateMerchantPayload) (*MerchantsResponse, error) {
+	merchant, err := s.repository.GetByUID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	err = Authorize(ctx, s.repository, merchant, permission.Write)
+	if err != nil {
+		return nil, err
+	}
+
+	// fields left out keep their current value, the result is validated like a new merchant
+	merged := CreateMerchantPayload{
+		Name:     merchant.Name,
+		Category: merchant.Category,
+		ImageURL: merchant.ImageURL,
+		Location: &Location{Lat: &merchant.Lat, Lng: &merchant.Lng},
+	}
+	if req.Name != nil {
+		merged.Name = *req.Name
+	}
+	if req.Category != nil {
+		merged.Category = *req.Category
+	}
+	if req.ImageURL != nil {
+		merged.ImageURL = *req.ImageURL
+	}
+	if req.Location != nil {
+		merged.Location = req.Location
+	}
+	err = merged.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+
+	merchant.Name = merged.Name
+	merchant.Category = merged.Category
+	merchant.ImageURL = merged.ImageURL
+	merchant.Lat = *merged.Location.Lat
+	merchant.Lng = *merged.Location.Lng
+	err = s.repository.Update(ctx, merchant)
+	if err != nil {
+		return nil, err
+	}
+
+	schedules, err := s.repository.ListSchedules(ctx, []uint64{merchant.ID})
+	if err != nil {
+		return nil, err
+	}
+	resp := CreateMerchantsResponse([]Merchants{*merchant}, schedules, time.Now())[0]
+	return &resp, nil
+}
+
+func (s *merchantService) Delete(ctx context.Context, uid string) error {
+	return s.repository.Delete(ctx, uid)
+}
+
+func (s *merchantService) Restore(ctx context.Context, uid string) error {
+	return s.repository.Restore(ctx, uid)
+}
+
+func (s *merchantService) GetSchedule(ctx context.Context, uid string) (*ScheduleResponse, error) {
+	merchant, err := s.repository.GetByUID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	err = Authorize(ctx, s.repository, merchant, permission.Read)
+	if err != nil {
+		return nil, err
+	}
+	return s.getSchedule(ctx, merchant.ID)
+}
+
+func (s *merchantService) UpdateOpeningHours(ctx context.Context, uid string, req UpdateOpeningHoursPayload) (*ScheduleResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	merchant, err := s.repository.GetByUID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	err = Authorize(ctx, s.repository, merchant, permission.Write)
+	if err != nil {
+		return nil, err
+	}
+	openingHours := make([]OpeningHours, 0, len(req.OpeningHours))
+	for _, h := range req.OpeningHours {
+		// already validated
+		opensMinute, _ := ParseMinute(h.OpensAt)
+		closesMinute, _ := ParseMinute(h.ClosesAt)
+		openingHours = append(openingHours, OpeningHours{
+			DayOfWeek:    time.Weekday(*h.DayOfWeek),
+			OpensMinute:  opensMinute,
+			ClosesMinute: closesMinute,
+		})
+	}
+	err = s.transactor.StartTx(ctx, func(tx *sql.Tx) error {
+		return s.repository.WithTx(tx).ReplaceOpeningHours(ctx, merchant.ID, req.Timezone, openingHours)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.getSchedule(ctx, merchant.ID)
+}
+
+func (s *merchantService) CreateClosure(ctx context.Context, uid string, req CreateClosurePayload) (*ScheduleResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	merchant, err := s.repository.GetByUID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	err = Authorize(ctx, s.repository, merchant, permission.Write)
+	if err != nil {
+		return nil, err
+	}
+	// already validated
+	date, _ := time.Parse(DateFormat, req.Date)
+	err = s.repository.InsertClosure(ctx, merchant.ID, Closure{
+		Date:   date,
+		Reason: req.Reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.getSchedule(ctx, merchant.ID)
+}
+
+func (s *merchantService) DeleteClosure(ctx context.Context, uid string, date string) error {
+	parsedDate, err := time.Parse(DateFormat, date)
+	if err != nil {
+		return fmt.Errorf("%w: date must be formatted as %s", ErrValidationFailed, DateFormat)
+	}
+	merchant, err := s.repository.GetByUID(ctx, uid)
+	if err != nil {
+		return err
+	}
+	err = Authorize(ctx, s.repository, merchant, permission.Write)
+	if err != nil {
+		return err
+	}
+	return s.repository.DeleteClosure(ctx, merchant.ID, parsedDate)
+}
+
+// ListMine lists the merchants the user is a member of.
+func (s *merchantService) ListMine(ctx context.Context) ([]MerchantsResponse, error) {
+	claims, ok := middleware.GetClaims(ctx)
+	if !ok {
+		return nil, ErrForbidden
+	}
+	merchants, err := s.repository.ListByMember(ctx, claims.UserUID)
+	if err != nil {
+		return nil, err
+	}
+	merchantIDs := make([]uint64, 0, len(merchants))
+	for _, merchant := range merchants {
+		merchantIDs = append(merchantIDs, merchant.ID)
+	}
+	schedules, err := s.repository.ListSchedules(ctx, merchantIDs)
+	if err != nil {
+		return nil, err
+	}
+	return CreateMerchantsResponse(merchants, schedules, time.Now()), nil
+}
+
+func (s *merchantService) ListMembers(ctx context.Context, uid string) ([]MemberResponse, error) {
+	merchant, err := s.repository.GetByUID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	err = Authorize(ctx, s.repository, merchant, permission.Read)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.repository.ListMembers(ctx, merchant.ID)
+	if err != nil {
+		return nil, err
+	}
+	return CreateMembersResponse(members), nil
+}
+
+// AddMember links the user to the merchant and returns every member. Members
+// cannot add members themselves, it takes the permission over all merchants.
+func (s *merchantService) AddMember(ctx context.Context, uid string, req AddMemberPayload) ([]MemberResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	merchant, err := s.repository.GetByUID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	err = authorizeAny(ctx, permission.Write)
+	if err != nil {
+		return nil, err
+	}
+	err = s.repository.AddMember(ctx, merchant.ID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return s.ListMembers(ctx, uid)
+}
+
+func (s *merchantService) RemoveMember(ctx context.Context, uid string, userUID string) error {
+	merchant, err := s.repository.GetByUID(ctx, uid)
+	if err != nil {
+		return err
+	}
+	err = authorizeAny(ctx, permission.Write)
+	if err != nil {
+		return err
+	}
+	return s.repository.RemoveMember(ctx, merchant.ID, userUID)
+}
 
-	return CreateMerchantsWithItemsResponse(merchantsWithItem), pagination, nil
+func (s *merchantService) getSchedule(ctx context.Context, merchantID uint64) (*ScheduleResponse, error) {
+	schedules, err := s.repository.ListSchedules(ctx, []uint64{merchantID})
+	if err != nil {
+		return nil, err
+	}
+	schedule, ok := schedules[merchantID]
+	if !ok {
+		return nil, ErrMerchantNotFound
+	}
+	return CreateScheduleResponse(schedule, time.Now()), nil
 }
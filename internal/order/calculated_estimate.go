@@ -5,31 +5,87 @@ import (
 	"encoding/json"
 	"errors"
 	"time"
+
+	"github.com/citadel-corp/belimang/internal/common/env"
+	merchantitems "github.com/citadel-corp/belimang/internal/merchant_items"
 )
 
+// CalculatedEstimateTTL is how long a calculated estimate can be turned into an order.
+// It is set at startup from LoadCalculatedEstimateTTL.
+var CalculatedEstimateTTL = 30 * time.Minute
+
+// LoadCalculatedEstimateTTL reads the CALCULATED_ESTIMATE_TTL env, e.g. "15m",
+// defaulting to 30 minutes.
+func LoadCalculatedEstimateTTL() (time.Duration, error) {
+	ttl, err := env.Duration("CALCULATED_ESTIMATE_TTL", 30*time.Minute)
+	if err != nil {
+		return ttl, err
+	}
+	if ttl <= 0 {
+		return ttl, errors.New("CALCULATED_ESTIMATE_TTL must be positive")
+	}
+	return ttl, nil
+}
+
 type CalculatedEstimate struct {
 	ID                    string
 	UserID                string
 	TotalPrice            int
+	PriceBreakdown        PriceBreakdown
+	PromotionID           *string
 	Lat                   float64
 	Long                  float64
 	Merchants             CalculatedEstimateMerchants
 	Items                 Items
 	EstimatedDeliveryTime int
+	Route                 EstimateRoute
 	Ordered               bool
+	ExpiresAt             time.Time
 	CreatedAt             time.Time
 }
 
 type CalculatedEstimateMerchants []string
 
+// Item is an ordered item. The item details are snapshotted when the estimate is
+// calculated so later changes to the merchant item do not rewrite order history.
 type Item struct {
-	ItemID     string `json:"itemId"`
-	MerchantID string `json:"merchantId"`
-	Quantity   int    `json:"quantity"`
+	ItemID     string                     `json:"itemId"`
+	MerchantID string                     `json:"merchantId"`
+	Quantity   int                        `json:"quantity"`
+	Name       string                     `json:"name,omitempty"`
+	Category   merchantitems.ItemCategory `json:"productCategory,omitempty"`
+	Price      int                        `json:"price"` // unit price when the estimate was calculated
+	ImageURL   string                     `json:"imageUrl,omitempty"`
+	Options    []ItemOption               `json:"options,omitempty"`
+	LineTotal  int                        `json:"lineTotal,omitempty"`
+	CreatedAt  time.Time                  `json:"createdAt"` // of the merchant item
+}
+
+// ItemOption is an option selected for an item, already included in the item price.
+type ItemOption struct {
+	OptionID   string `json:"optionId"`
+	GroupName  string `json:"groupName"`
+	Name       string `json:"name"`
+	PriceDelta int    `json:"priceDelta"`
 }
 
 type Items []Item
 
+// EstimateRoute is the planned pickup route. Every stop carries the distance of the
+// leg leading to it and the minutes elapsed since leaving the starting merchant.
+type EstimateRoute struct {
+	Stops       []RouteStop `json:"stops"`
+	Destination RouteStop   `json:"destination"`
+}
+
+type RouteStop struct {
+	MerchantID          string  `json:"merchantId,omitempty"`
+	Lat                 float64 `json:"lat"`
+	Long                float64 `json:"long"`
+	LegDistanceInMeters int     `json:"legDistanceInMeters"`
+	CumulativeMinutes   int     `json:"cumulativeMinutes"`
+}
+
 // Make the Attrs struct implement the driver.Valuer interface. This method
 // simply returns the JSON-encoded representation of the struct.
 func (a CalculatedEstimateMerchants) Value() (driver.Value, error) {
@@ -63,3 +119,25 @@ func (a *Items) Scan(value interface{}) error {
 
 	return json.Unmarshal(b, &a)
 }
+
+// Make the Attrs struct implement the driver.Valuer interface. This method
+// simply returns the JSON-encoded representation of the struct.
+func (a EstimateRoute) Value() (driver.Value, error) {
+	return json.Marshal(a)
+}
+
+// Make the Attrs struct implement the sql.Scanner interface. This method
+// simply decodes a JSON-encoded value into the struct fields. Estimates created
+// before routes were stored have no route.
+func (a *EstimateRoute) Scan(value interface{}) error {
+	if value == nil {
+		*a = EstimateRoute{}
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(b, &a)
+}
@@ -6,7 +6,17 @@ var (
 	ErrValidationFailed           = errors.New("validation failed")
 	ErrStartingPointInvalid       = errors.New("starting point must be exactly 1")
 	ErrSomeMerchantNotFound       = errors.New("some merchants are not found")
+	ErrMerchantClosed             = errors.New("merchant is closed")
 	ErrSomeItemNotFound           = errors.New("some items are not found")
+	ErrSomeItemUnavailable        = errors.New("some items are not available")
+	ErrItemMerchantMismatch       = errors.New("item does not belong to the merchant it is ordered from")
 	ErrDistanceTooFar             = errors.New("distance too far")
+	ErrMinimumOrderNotMet         = errors.New("minimum order not met")
 	ErrCalculatedEstimateNotFound = errors.New("calculated estimate not found")
+	ErrCalculatedEstimateExpired  = errors.New("calculated estimate has expired")
+	ErrCalculatedEstimateOrdered  = errors.New("calculated estimate has already been ordered")
+	ErrCalculatedEstimateNotOwned = errors.New("calculated estimate belongs to another user")
+	ErrOrderNotFound              = errors.New("order not found")
+	ErrInvalidStatusTransition    = errors.New("invalid order status transition")
+	ErrOrderStatusConflict        = errors.New("order status has been changed by another request")
 )
@@ -9,6 +9,10 @@ import (
 	"github.com/citadel-corp/belimang/internal/common/middleware"
 	"github.com/citadel-corp/belimang/internal/common/request"
 	"github.com/citadel-corp/belimang/internal/common/response"
+	merchantitems "github.com/citadel-corp/belimang/internal/merchant_items"
+	"github.com/citadel-corp/belimang/internal/merchants"
+	"github.com/citadel-corp/belimang/internal/promotions"
+	"github.com/gorilla/mux"
 	"github.com/gorilla/schema"
 )
 
@@ -45,6 +49,20 @@ func (h *Handler) CalculateEstimate(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	if errors.Is(err, merchantitems.ErrInvalidOptionSelection) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrMinimumOrderNotMet) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
 	if errors.Is(err, haversine.ErrDistanceTooFar) {
 		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
 			Message: "Bad request",
@@ -52,6 +70,22 @@ func (h *Handler) CalculateEstimate(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	if errors.Is(err, promotions.ErrPromotionNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, promotions.ErrPromotionNotActive) || errors.Is(err, promotions.ErrPromotionUsageLimitReached) ||
+		errors.Is(err, promotions.ErrPromotionUserLimitReached) || errors.Is(err, promotions.ErrPromotionNotApplicable) ||
+		errors.Is(err, promotions.ErrMinSpendNotMet) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
 	if errors.Is(err, ErrSomeMerchantNotFound) {
 		response.JSON(w, http.StatusNotFound, response.ResponseBody{
 			Message: "Not found",
@@ -66,6 +100,20 @@ func (h *Handler) CalculateEstimate(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	if errors.Is(err, ErrMerchantClosed) {
+		response.JSON(w, http.StatusConflict, response.ResponseBody{
+			Message: "Conflict",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrSomeItemUnavailable) {
+		response.JSON(w, http.StatusConflict, response.ResponseBody{
+			Message: "Conflict",
+			Error:   err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
 			Message: "Internal server error",
@@ -108,6 +156,42 @@ func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	if errors.Is(err, ErrSomeMerchantNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrCalculatedEstimateNotOwned) {
+		response.JSON(w, http.StatusForbidden, response.ResponseBody{
+			Message: "Forbidden",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrCalculatedEstimateOrdered) {
+		response.JSON(w, http.StatusConflict, response.ResponseBody{
+			Message: "Conflict",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, promotions.ErrPromotionNotFound) || errors.Is(err, promotions.ErrPromotionNotActive) ||
+		errors.Is(err, promotions.ErrPromotionUsageLimitReached) || errors.Is(err, promotions.ErrPromotionUserLimitReached) {
+		response.JSON(w, http.StatusConflict, response.ResponseBody{
+			Message: "Conflict",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrCalculatedEstimateExpired) {
+		response.JSON(w, http.StatusGone, response.ResponseBody{
+			Message: "Gone",
+			Error:   err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
 			Message: "Internal server error",
@@ -134,7 +218,14 @@ func (h *Handler) SearchOrders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	orders, err := h.service.SearchOrders(r.Context(), req, userID)
+	orders, pagination, err := h.service.SearchOrders(r.Context(), req, userID)
+	if errors.Is(err, response.ErrInvalidCursor) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
 			Message: "Internal server error",
@@ -143,9 +234,91 @@ func (h *Handler) SearchOrders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// offset pages keep their bare array body, cursor pages need the meta for the next cursor
+	if req.Cursor != nil {
+		response.JSON(w, http.StatusOK, response.ResponseBody{
+			Data: orders,
+			Meta: pagination,
+		})
+		return
+	}
 	response.JSON(w, http.StatusOK, orders)
 }
 
+func (h *Handler) GetOrderStatus(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r)
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{})
+		return
+	}
+
+	res, err := h.service.GetOrderStatus(r.Context(), mux.Vars(r)["orderId"], userID)
+	if errors.Is(err, ErrOrderNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r)
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{})
+		return
+	}
+	var req UpdateOrderStatusRequest
+
+	err = request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	res, err := h.service.UpdateOrderStatus(r.Context(), mux.Vars(r)["orderId"], req, userID)
+	if errors.Is(err, ErrValidationFailed) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrOrderNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrInvalidStatusTransition) || errors.Is(err, ErrOrderStatusConflict) {
+		response.JSON(w, http.StatusConflict, response.ResponseBody{
+			Message: "Conflict",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, res)
+}
+
 func getUserID(r *http.Request) (string, error) {
 	if authValue, ok := r.Context().Value(middleware.ContextAuthKey{}).(*jwt.UserClaims); ok {
 		return authValue.UserUID, nil
@@ -153,3 +326,106 @@ func getUserID(r *http.Request) (string, error) {
 		return "", errors.New("cannot parse auth value from context")
 	}
 }
+
+func (h *Handler) ListMerchantOrders(w http.ResponseWriter, r *http.Request) {
+	var req ListMerchantOrdersPayload
+
+	newSchema := schema.NewDecoder()
+	newSchema.IgnoreUnknownKeys(true)
+
+	if err := newSchema.Decode(&req, r.URL.Query()); err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{})
+		return
+	}
+
+	orders, pagination, err := h.service.ListMerchantOrders(r.Context(), mux.Vars(r)["merchantId"], req)
+	if errors.Is(err, ErrValidationFailed) || errors.Is(err, response.ErrInvalidCursor) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, merchants.ErrMerchantNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, merchants.ErrForbidden) {
+		response.JSON(w, http.StatusForbidden, response.ResponseBody{
+			Message: "Forbidden",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Data: orders,
+		Meta: pagination,
+	})
+}
+
+func (h *Handler) UpdateMerchantOrderStatus(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r)
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{})
+		return
+	}
+	var req UpdateOrderStatusRequest
+
+	err = request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	vars := mux.Vars(r)
+	res, err := h.service.UpdateMerchantOrderStatus(r.Context(), vars["merchantId"], vars["orderId"], req, userID)
+	if errors.Is(err, ErrValidationFailed) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, merchants.ErrMerchantNotFound) || errors.Is(err, ErrOrderNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, merchants.ErrForbidden) {
+		response.JSON(w, http.StatusForbidden, response.ResponseBody{
+			Message: "Forbidden",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrInvalidStatusTransition) || errors.Is(err, ErrOrderStatusConflict) {
+		response.JSON(w, http.StatusConflict, response.ResponseBody{
+			Message: "Conflict",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, res)
+}
@@ -1,12 +1,65 @@
 package order
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/citadel-corp/belimang/internal/merchants"
+)
+
+type OrderStatus string
+
+var (
+	Placed    OrderStatus = "Placed"
+	Accepted  OrderStatus = "Accepted"
+	Preparing OrderStatus = "Preparing"
+	PickedUp  OrderStatus = "PickedUp"
+	Delivered OrderStatus = "Delivered"
+	Cancelled OrderStatus = "Cancelled"
+	Rejected  OrderStatus = "Rejected"
+)
+
+var OrderStatuses = []interface{}{
+	Placed,
+	Accepted,
+	Preparing,
+	PickedUp,
+	Delivered,
+	Cancelled,
+	Rejected,
+}
+
+// orderStatusTransitions lists, for every status, the statuses an order may move to next.
+// Delivered, Cancelled and Rejected are terminal.
+var orderStatusTransitions = map[OrderStatus][]OrderStatus{
+	Placed:    {Accepted, Rejected, Cancelled},
+	Accepted:  {Preparing, Cancelled},
+	Preparing: {PickedUp, Cancelled},
+	PickedUp:  {Delivered},
+}
+
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, status := range orderStatusTransitions[s] {
+		if status == next {
+			return true
+		}
+	}
+	return false
+}
+
+func (s OrderStatus) IsTerminal() bool {
+	return len(orderStatusTransitions[s]) == 0
+}
 
 type Order struct {
 	ID                   string
 	CalculatedEstimateID string
 	UserID               string
+	Status               OrderStatus
 	CreatedAt            time.Time
+	UpdatedAt            time.Time
 }
 
 type OrderItem struct {
@@ -15,4 +68,47 @@ type OrderItem struct {
 	OrderID    string
 	MerchantID string
 	Items      Items
+	Merchant   MerchantSnapshot
+}
+
+// MerchantSnapshot is the merchant as it was when the order was placed.
+type MerchantSnapshot struct {
+	MerchantID string                     `json:"merchantId"`
+	Name       string                     `json:"name"`
+	Category   merchants.MerchantCategory `json:"merchantCategory"`
+	ImageURL   string                     `json:"imageUrl"`
+	Lat        float64                    `json:"lat"`
+	Long       float64                    `json:"long"`
+	CreatedAt  time.Time                  `json:"createdAt"`
+}
+
+// Make the Attrs struct implement the driver.Valuer interface. This method
+// simply returns the JSON-encoded representation of the struct.
+func (a MerchantSnapshot) Value() (driver.Value, error) {
+	return json.Marshal(a)
+}
+
+// Make the Attrs struct implement the sql.Scanner interface. This method
+// simply decodes a JSON-encoded value into the struct fields. Order items of
+// merchants removed before the backfill have no snapshot.
+func (a *MerchantSnapshot) Scan(value interface{}) error {
+	if value == nil {
+		*a = MerchantSnapshot{}
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(b, &a)
+}
+
+type OrderStatusHistory struct {
+	ID         uint64
+	OrderID    string
+	FromStatus *OrderStatus
+	ToStatus   OrderStatus
+	ChangedBy  string
+	CreatedAt  time.Time
 }
@@ -0,0 +1,132 @@
+package order
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/citadel-corp/belimang/internal/common/env"
+	"github.com/citadel-corp/belimang/internal/merchants"
+)
+
+// PricingConfig drives the fees added on top of the ordered items. Every fee
+// defaults to zero, in which case the total equals the items subtotal.
+type PricingConfig struct {
+	BaseDeliveryFee      int
+	PerKmFee             int
+	SurgeMultiplier      float64
+	ServiceFee           int
+	MinimumOrder         int
+	MerchantCategoryFees map[merchants.MerchantCategory]int
+	TaxPercentage        float64
+}
+
+// LoadPricingConfig reads the pricing configuration from env.
+// PRICING_MERCHANT_CATEGORY_FEES is a JSON object keyed by merchant category,
+// e.g. {"LargeRestaurant": 2000}.
+func LoadPricingConfig() (PricingConfig, error) {
+	var (
+		cfg = PricingConfig{
+			SurgeMultiplier:      1,
+			MerchantCategoryFees: make(map[merchants.MerchantCategory]int),
+		}
+		err error
+	)
+	if cfg.BaseDeliveryFee, err = getEnvFee("PRICING_BASE_DELIVERY_FEE", cfg.BaseDeliveryFee); err != nil {
+		return cfg, err
+	}
+	if cfg.PerKmFee, err = getEnvFee("PRICING_PER_KM_FEE", cfg.PerKmFee); err != nil {
+		return cfg, err
+	}
+	if cfg.SurgeMultiplier, err = env.Float("PRICING_SURGE_MULTIPLIER", cfg.SurgeMultiplier); err != nil {
+		return cfg, err
+	}
+	if cfg.ServiceFee, err = getEnvFee("PRICING_SERVICE_FEE", cfg.ServiceFee); err != nil {
+		return cfg, err
+	}
+	if cfg.MinimumOrder, err = getEnvFee("PRICING_MINIMUM_ORDER", cfg.MinimumOrder); err != nil {
+		return cfg, err
+	}
+	if cfg.TaxPercentage, err = env.Float("PRICING_TAX_PERCENTAGE", cfg.TaxPercentage); err != nil {
+		return cfg, err
+	}
+	if err = env.JSON("PRICING_MERCHANT_CATEGORY_FEES", &cfg.MerchantCategoryFees); err != nil {
+		return cfg, err
+	}
+	if cfg.SurgeMultiplier < 1 {
+		return cfg, errors.New("PRICING_SURGE_MULTIPLIER must be at least 1")
+	}
+	if cfg.TaxPercentage < 0 {
+		return cfg, errors.New("PRICING_TAX_PERCENTAGE must not be negative")
+	}
+	return cfg, nil
+}
+
+// PriceBreakdown itemizes the total price of an estimate.
+type PriceBreakdown struct {
+	Subtotal     int    `json:"subtotal"`
+	DeliveryFee  int    `json:"deliveryFee"`
+	ServiceFee   int    `json:"serviceFee"`
+	MerchantFees int    `json:"merchantFees"`
+	Discount     int    `json:"discount"`
+	VoucherCode  string `json:"voucherCode,omitempty"`
+	Tax          int    `json:"tax"`
+	Total        int    `json:"total"`
+}
+
+// Price computes the breakdown for an items subtotal delivered over distance
+// kilometers from the given merchants. The discount only reduces the taxed items
+// amount; fees are charged in full.
+func (c PricingConfig) Price(subtotal int, discount int, distance float64, merchantList []*merchants.Merchants) (PriceBreakdown, error) {
+	if subtotal < c.MinimumOrder {
+		return PriceBreakdown{}, fmt.Errorf("%w: subtotal %d is below the minimum order of %d", ErrMinimumOrderNotMet, subtotal, c.MinimumOrder)
+	}
+	breakdown := PriceBreakdown{
+		Subtotal:    subtotal,
+		DeliveryFee: int(math.Round((float64(c.BaseDeliveryFee) + float64(c.PerKmFee)*distance) * c.SurgeMultiplier)),
+		ServiceFee:  c.ServiceFee,
+		Discount:    discount,
+		Tax:         int(math.Round(float64(subtotal-discount) * c.TaxPercentage / 100)),
+	}
+	for _, merchant := range merchantList {
+		breakdown.MerchantFees += c.MerchantCategoryFees[merchant.Category]
+	}
+	breakdown.Total = breakdown.Subtotal + breakdown.DeliveryFee + breakdown.ServiceFee + breakdown.MerchantFees + breakdown.Tax - breakdown.Discount
+	return breakdown, nil
+}
+
+// Make the Attrs struct implement the driver.Valuer interface. This method
+// simply returns the JSON-encoded representation of the struct.
+func (a PriceBreakdown) Value() (driver.Value, error) {
+	return json.Marshal(a)
+}
+
+// Make the Attrs struct implement the sql.Scanner interface. This method
+// simply decodes a JSON-encoded value into the struct fields. Estimates created
+// before breakdowns were stored have none.
+func (a *PriceBreakdown) Scan(value interface{}) error {
+	if value == nil {
+		*a = PriceBreakdown{}
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(b, &a)
+}
+
+// getEnvFee reads a fee, which must not be negative.
+func getEnvFee(key string, fallback int) (int, error) {
+	fee, err := env.Int(key, fallback)
+	if err != nil {
+		return fallback, err
+	}
+	if fee < 0 {
+		return fallback, fmt.Errorf("%s must not be negative", key)
+	}
+	return fee, nil
+}
@@ -4,41 +4,56 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
 	"strings"
 	"time"
 
 	"github.com/citadel-corp/belimang/internal/common/db"
+	"github.com/citadel-corp/belimang/internal/common/response"
 )
 
 type Repository interface {
+	WithTx(tx *sql.Tx) Repository
 	InsertCalculatedEstimate(ctx context.Context, calculatedEstimate *CalculatedEstimate) error
 	GetCalculatedEstimate(ctx context.Context, id string) (*CalculatedEstimate, error)
+	ClaimCalculatedEstimate(ctx context.Context, id string, userID string) error
 	InsertOrder(ctx context.Context, order *Order) error
 	InsertOrderItem(ctx context.Context, orderItem *OrderItem) error
+	GetOrderByID(ctx context.Context, id string) (*Order, error)
+	UpdateOrderStatus(ctx context.Context, history *OrderStatusHistory) error
+	InsertOrderStatusHistory(ctx context.Context, history *OrderStatusHistory) error
+	ListOrderStatusHistories(ctx context.Context, orderID string) ([]*OrderStatusHistory, error)
 	ListOrdersByUserID(ctx context.Context, userID string) (*Order, error)
 	ListOrderItemsByOrderID(ctx context.Context, orderID string) ([]*OrderItem, error)
-	SearchOrderItemMerchants(ctx context.Context, req SearchOrderPayload, userID string) ([]*searchOrderItemMerchantsQueryResult, error)
+	SearchOrderItemMerchants(ctx context.Context, req SearchOrderPayload, userID string) ([]*searchOrderItemMerchantsQueryResult, *response.Pagination, error)
+	ListMerchantOrders(ctx context.Context, merchantUID string, req ListMerchantOrdersPayload) ([]*merchantOrderQueryResult, *response.Pagination, error)
 }
 
 type dbRepository struct {
 	db *db.DB
+	tx *sql.Tx
 }
 
 func NewRepository(db *db.DB) Repository {
 	return &dbRepository{db: db}
 }
 
+// WithTx implements Repository.
+func (d *dbRepository) WithTx(tx *sql.Tx) Repository {
+	return &dbRepository{db: d.db, tx: tx}
+}
+
 // InsertCalculatedEstimate implements Repository.
 func (d *dbRepository) InsertCalculatedEstimate(ctx context.Context, calculatedEstimate *CalculatedEstimate) error {
 	q := `
 	    INSERT INTO calculated_estimates  (
-            id, user_id, total_price, user_location_lat, user_location_lng, estimated_delivery_time, ordered, merchants, items
+            id, user_id, total_price, price_breakdown, promotion_id, user_location_lat, user_location_lng, estimated_delivery_time, ordered, merchants, items, route, expires_at
         ) VALUES (
-            $1, $2, $3, $4, $5, $6, $7, $8, $9
-        );
+            $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, current_timestamp + make_interval(secs => $13)
+        )
+		RETURNING expires_at, created_at;
 	`
-	_, err := d.db.DB().ExecContext(ctx, q, calculatedEstimate.ID, calculatedEstimate.UserID, calculatedEstimate.TotalPrice, calculatedEstimate.Lat, calculatedEstimate.Long, calculatedEstimate.EstimatedDeliveryTime, calculatedEstimate.Ordered, calculatedEstimate.Merchants, calculatedEstimate.Items)
+	row := d.db.Executor(d.tx).QueryRowContext(ctx, q, calculatedEstimate.ID, calculatedEstimate.UserID, calculatedEstimate.TotalPrice, calculatedEstimate.PriceBreakdown, calculatedEstimate.PromotionID, calculatedEstimate.Lat, calculatedEstimate.Long, calculatedEstimate.EstimatedDeliveryTime, calculatedEstimate.Ordered, calculatedEstimate.Merchants, calculatedEstimate.Items, calculatedEstimate.Route, CalculatedEstimateTTL.Seconds())
+	err := row.Scan(&calculatedEstimate.ExpiresAt, &calculatedEstimate.CreatedAt)
 	if err != nil {
 		return err
 	}
@@ -48,13 +63,13 @@ func (d *dbRepository) InsertCalculatedEstimate(ctx context.Context, calculatedE
 // GetCalculatedEstimate implements Repository.
 func (d *dbRepository) GetCalculatedEstimate(ctx context.Context, id string) (*CalculatedEstimate, error) {
 	q := `
-	    SELECT id, user_id, total_price, user_location_lat, user_location_lng, estimated_delivery_time, ordered, merchants, items
+	    SELECT id, user_id, total_price, price_breakdown, promotion_id, user_location_lat, user_location_lng, estimated_delivery_time, ordered, merchants, items, route, expires_at, created_at
 		FROM calculated_estimates
         WHERE id = $1;
 	`
-	row := d.db.DB().QueryRowContext(ctx, q, id)
+	row := d.db.Executor(d.tx).QueryRowContext(ctx, q, id)
 	calculatedEstimate := &CalculatedEstimate{}
-	err := row.Scan(&calculatedEstimate.ID, &calculatedEstimate.UserID, &calculatedEstimate.TotalPrice, &calculatedEstimate.Lat, &calculatedEstimate.Long, &calculatedEstimate.EstimatedDeliveryTime, &calculatedEstimate.Ordered, &calculatedEstimate.Merchants, &calculatedEstimate.Items)
+	err := row.Scan(&calculatedEstimate.ID, &calculatedEstimate.UserID, &calculatedEstimate.TotalPrice, &calculatedEstimate.PriceBreakdown, &calculatedEstimate.PromotionID, &calculatedEstimate.Lat, &calculatedEstimate.Long, &calculatedEstimate.EstimatedDeliveryTime, &calculatedEstimate.Ordered, &calculatedEstimate.Merchants, &calculatedEstimate.Items, &calculatedEstimate.Route, &calculatedEstimate.ExpiresAt, &calculatedEstimate.CreatedAt)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrCalculatedEstimateNotFound
 	}
@@ -64,16 +79,65 @@ func (d *dbRepository) GetCalculatedEstimate(ctx context.Context, id string) (*C
 	return calculatedEstimate, nil
 }
 
+// ClaimCalculatedEstimate implements Repository.
+// It flips ordered on a calculated estimate that belongs to userID, has not been
+// ordered and has not expired. When nothing is claimed, the estimate is read again
+// to report why. Run it in the same transaction as the order inserts.
+func (d *dbRepository) ClaimCalculatedEstimate(ctx context.Context, id string, userID string) error {
+	q := `
+		UPDATE calculated_estimates
+		SET ordered = true
+		WHERE id = $1 AND user_id = $2 AND ordered = false AND expires_at > current_timestamp;
+	`
+	res, err := d.db.Executor(d.tx).ExecContext(ctx, q, id, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 1 {
+		return nil
+	}
+
+	q = `
+		SELECT user_id, ordered, expires_at <= current_timestamp
+		FROM calculated_estimates
+		WHERE id = $1;
+	`
+	var (
+		ownerID          string
+		ordered, expired bool
+	)
+	err = d.db.Executor(d.tx).QueryRowContext(ctx, q, id).Scan(&ownerID, &ordered, &expired)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrCalculatedEstimateNotFound
+	}
+	if err != nil {
+		return err
+	}
+	switch {
+	case ownerID != userID:
+		return ErrCalculatedEstimateNotOwned
+	case ordered:
+		return ErrCalculatedEstimateOrdered
+	case expired:
+		return ErrCalculatedEstimateExpired
+	}
+	return ErrCalculatedEstimateNotFound
+}
+
 // InsertOrder implements Repository.
 func (d *dbRepository) InsertOrder(ctx context.Context, order *Order) error {
 	q := `
 	    INSERT INTO orders (
-            id, calculated_estimate_id, user_id
+            id, calculated_estimate_id, user_id, status
         ) VALUES (
-            $1, $2, $3
+            $1, $2, $3, $4
         );
 	`
-	_, err := d.db.DB().ExecContext(ctx, q, order.ID, order.CalculatedEstimateID, order.UserID)
+	_, err := d.db.Executor(d.tx).ExecContext(ctx, q, order.ID, order.CalculatedEstimateID, order.UserID, order.Status)
 	if err != nil {
 		return err
 	}
@@ -84,18 +148,101 @@ func (d *dbRepository) InsertOrder(ctx context.Context, order *Order) error {
 func (d *dbRepository) InsertOrderItem(ctx context.Context, orderItem *OrderItem) error {
 	q := `
 	    INSERT INTO order_items (
-            id, order_id, merchant_id, items
+            id, order_id, merchant_id, items, merchant
+        ) VALUES (
+            $1, $2, $3, $4, $5
+        );
+	`
+	_, err := d.db.Executor(d.tx).ExecContext(ctx, q, orderItem.ID, orderItem.OrderID, orderItem.MerchantID, orderItem.Items, orderItem.Merchant)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetOrderByID implements Repository.
+func (d *dbRepository) GetOrderByID(ctx context.Context, id string) (*Order, error) {
+	q := `
+	    SELECT id, calculated_estimate_id, user_id, status, created_at, updated_at
+		FROM orders
+		WHERE id = $1;
+	`
+	row := d.db.Executor(d.tx).QueryRowContext(ctx, q, id)
+	o := &Order{}
+	err := row.Scan(&o.ID, &o.CalculatedEstimateID, &o.UserID, &o.Status, &o.CreatedAt, &o.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// UpdateOrderStatus implements Repository.
+// The order is only updated when it is still in history.FromStatus, so concurrent
+// transitions cannot skip a step of the state machine.
+func (d *dbRepository) UpdateOrderStatus(ctx context.Context, history *OrderStatusHistory) error {
+	q := `
+	    UPDATE orders
+		SET status = $1, updated_at = current_timestamp
+		WHERE id = $2 AND status = $3;
+	`
+	res, err := d.db.Executor(d.tx).ExecContext(ctx, q, history.ToStatus, history.OrderID, history.FromStatus)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrOrderStatusConflict
+	}
+	return nil
+}
+
+// InsertOrderStatusHistory implements Repository.
+func (d *dbRepository) InsertOrderStatusHistory(ctx context.Context, history *OrderStatusHistory) error {
+	q := `
+	    INSERT INTO order_status_histories (
+            order_id, from_status, to_status, changed_by
         ) VALUES (
             $1, $2, $3, $4
         );
 	`
-	_, err := d.db.DB().ExecContext(ctx, q, orderItem.ID, orderItem.OrderID, orderItem.MerchantID, orderItem.Items)
+	_, err := d.db.Executor(d.tx).ExecContext(ctx, q, history.OrderID, history.FromStatus, history.ToStatus, history.ChangedBy)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// ListOrderStatusHistories implements Repository.
+func (d *dbRepository) ListOrderStatusHistories(ctx context.Context, orderID string) ([]*OrderStatusHistory, error) {
+	q := `
+	    SELECT id, order_id, from_status, to_status, changed_by, created_at
+		FROM order_status_histories
+		WHERE order_id = $1
+		ORDER BY created_at ASC, id ASC;
+	`
+	rows, err := d.db.Executor(d.tx).QueryContext(ctx, q, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := make([]*OrderStatusHistory, 0)
+	for rows.Next() {
+		h := &OrderStatusHistory{}
+		err = rows.Scan(&h.ID, &h.OrderID, &h.FromStatus, &h.ToStatus, &h.ChangedBy, &h.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, h)
+	}
+	return res, nil
+}
+
 // ListOrdersByUserID implements Repository.
 func (d *dbRepository) ListOrdersByUserID(ctx context.Context, userID string) (*Order, error) {
 	q := `
@@ -103,7 +250,7 @@ func (d *dbRepository) ListOrdersByUserID(ctx context.Context, userID string) (*
 		FROM orders
 		WHERE user_id = $1;
 	`
-	row := d.db.DB().QueryRowContext(ctx, q, userID)
+	row := d.db.Executor(d.tx).QueryRowContext(ctx, q, userID)
 	o := &Order{}
 	err := row.Scan(&o.ID, &o.CalculatedEstimateID, &o.UserID)
 	if err != nil {
@@ -115,80 +262,218 @@ func (d *dbRepository) ListOrdersByUserID(ctx context.Context, userID string) (*
 // ListOrderItemsByOrderID implements Repository.
 func (d *dbRepository) ListOrderItemsByOrderID(ctx context.Context, orderID string) ([]*OrderItem, error) {
 	q := `
-	    SELECT id, order_id, merchant_id, items
+	    SELECT id, order_id, merchant_id, items, merchant
 		FROM order_items
 		WHERE order_id = $1;
 	`
-	rows, err := d.db.DB().QueryContext(ctx, q, orderID)
+	rows, err := d.db.Executor(d.tx).QueryContext(ctx, q, orderID)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 	res := make([]*OrderItem, 0)
 	for rows.Next() {
 		o := &OrderItem{}
-		err = rows.Scan(&o.ID, &o.OrderID, &o.MerchantID, &o.Items)
+		err = rows.Scan(&o.ID, &o.OrderID, &o.MerchantID, &o.Items, &o.Merchant)
 		if err != nil {
 			return nil, err
 		}
 		res = append(res, o)
 	}
-
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
 	return res, nil
 }
 
 // SearchOrderItemMerchants implements Repository.
-func (d *dbRepository) SearchOrderItemMerchants(ctx context.Context, req SearchOrderPayload, userID string) ([]*searchOrderItemMerchantsQueryResult, error) {
-	paramNo := 1
-	listQuery := `
-		SELECT o.id, oi.items, m.uid, m.name, m.merchant_category, m.image_url, m.location_lat, m.location_lng, m.created_at
-		FROM order_items oi
-		INNER JOIN orders o on oi.order_id = o.id
-		INNER JOIN merchants m on oi.merchant_id = m.uid
-		WHERE `
-	params := make([]interface{}, 0)
+// Orders are searched and rendered from the snapshots taken when they were placed.
+// A page holds whole orders: the orders are paged first, then their items fetched.
+func (d *dbRepository) SearchOrderItemMerchants(ctx context.Context, req SearchOrderPayload, userID string) ([]*searchOrderItemMerchantsQueryResult, *response.Pagination, error) {
+	itemConditions, itemArgs := make([]string, 0), make([]any, 0)
 	if req.MerchantID != "" {
-		listQuery += fmt.Sprintf("m.uid = $%d AND ", paramNo)
-		paramNo += 1
-		params = append(params, req.MerchantID)
+		itemConditions = append(itemConditions, "oi.merchant_id = ?")
+		itemArgs = append(itemArgs, req.MerchantID)
 	}
 	if req.Name != "" {
-		listQuery += fmt.Sprintf("m.name ILIKE '%%%s%%' AND ", req.Name)
+		itemConditions = append(itemConditions, "oi.merchant->>'name' ILIKE ?")
+		itemArgs = append(itemArgs, "%"+escapeLike(req.Name)+"%")
 	}
 	if req.MerchantCategory != "" {
-		listQuery += fmt.Sprintf("m.merchant_category = $%d AND ", paramNo)
-		paramNo += 1
-		params = append(params, req.MerchantCategory)
+		itemConditions = append(itemConditions, "oi.merchant->>'merchantCategory' = ?")
+		itemArgs = append(itemArgs, req.MerchantCategory)
+	}
+
+	q := db.NewQuery(`
+		SELECT o.id, o.created_at
+		FROM orders o
+	`)
+	q.Where("o.user_id = ?", userID)
+	q.Where("EXISTS (SELECT 1 FROM order_items oi WHERE "+
+		strings.Join(append([]string{"oi.order_id = o.id"}, itemConditions...), " AND ")+")", itemArgs...)
+	if req.Cursor != nil {
+		after, err := response.DecodeCreatedAtCursor(*req.Cursor)
+		if err != nil {
+			return nil, nil, err
+		}
+		if after != nil {
+			q.Where("(o.created_at, o.id) < (?, ?)", *after.CreatedAt, after.ID)
+		}
 	}
-	listQuery += fmt.Sprintf("o.user_id = $%d ", paramNo)
-	params = append(params, userID)
-	if strings.HasSuffix(listQuery, "AND ") {
-		listQuery, _ = strings.CutSuffix(listQuery, "AND ")
+	q.OrderBy("o.created_at DESC").OrderBy("o.id DESC")
+	if req.Cursor != nil {
+		// one extra order tells whether there is a next page
+		q.Limit(req.Limit + 1)
+	} else {
+		q.Page(req.Limit, req.Offset)
 	}
-	listQuery += fmt.Sprintf(" LIMIT %d OFFSET %d;", req.Limit, req.Offset)
-	rows, err := d.db.DB().QueryContext(ctx, listQuery, params...)
+
+	query, args := q.Build()
+	rows, err := d.db.Executor(d.tx).QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	res := make([]*searchOrderItemMerchantsQueryResult, 0)
+	defer rows.Close()
+	orderIDs := make([]string, 0)
+	orderCreatedAts := make(map[string]time.Time) // key: order id
 	for rows.Next() {
+		var orderID string
+		var createdAt time.Time
+		err = rows.Scan(&orderID, &createdAt)
+		if err != nil {
+			return nil, nil, err
+		}
+		orderIDs = append(orderIDs, orderID)
+		orderCreatedAts[orderID] = createdAt
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	pagination := &response.Pagination{Limit: req.Limit}
+	if req.Cursor == nil {
+		pagination.Offset = req.Offset
+	} else if len(orderIDs) > req.Limit {
+		orderIDs = orderIDs[:req.Limit]
+		last := orderIDs[len(orderIDs)-1]
+		createdAt := orderCreatedAts[last]
+		pagination.NextCursor = response.Cursor{CreatedAt: &createdAt, ID: last}.Encode()
+	}
+	if len(orderIDs) == 0 {
+		return []*searchOrderItemMerchantsQueryResult{}, pagination, nil
+	}
+
+	itemsQuery := db.NewQuery(`
+		SELECT oi.order_id, oi.id, oi.items, oi.merchant
+		FROM order_items oi
+	`)
+	itemsQuery.WhereAny("oi.order_id", orderIDs)
+	if len(itemConditions) > 0 {
+		itemsQuery.Where(strings.Join(itemConditions, " AND "), itemArgs...)
+	}
+	itemsQuery.OrderBy("oi.id")
+
+	query, args = itemsQuery.Build()
+	itemRows, err := d.db.Executor(d.tx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer itemRows.Close()
+	orderItems := make(map[string][]*searchOrderItemMerchantsQueryResult) // key: order id
+	for itemRows.Next() {
 		o := &searchOrderItemMerchantsQueryResult{}
-		err = rows.Scan(&o.OrderID, &o.OrderItems, &o.MerchantID, &o.MerchantName, &o.MerchantCategory, &o.MerchantImageURL, &o.MerchantLat, &o.MerchantLong, &o.MerchantCreatedAt)
+		err = itemRows.Scan(&o.OrderID, &o.OrderItemID, &o.OrderItems, &o.Merchant)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		res = append(res, o)
+		o.OrderCreatedAt = orderCreatedAts[o.OrderID]
+		orderItems[o.OrderID] = append(orderItems[o.OrderID], o)
 	}
-	return res, nil
+	if err = itemRows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	res := make([]*searchOrderItemMerchantsQueryResult, 0)
+	for _, orderID := range orderIDs {
+		res = append(res, orderItems[orderID]...)
+	}
+	return res, pagination, nil
 }
 
 type searchOrderItemMerchantsQueryResult struct {
-	OrderID           string
-	OrderItems        Items
-	MerchantID        string
-	MerchantName      string
-	MerchantCategory  string
-	MerchantImageURL  string
-	MerchantLat       float64
-	MerchantLong      float64
-	MerchantCreatedAt time.Time
+	OrderID        string
+	OrderCreatedAt time.Time
+	OrderItemID    string
+	OrderItems     Items
+	Merchant       MerchantSnapshot
+}
+
+// ListMerchantOrders implements Repository.
+// Orders come with the items of the merchant only, newest first.
+func (d *dbRepository) ListMerchantOrders(ctx context.Context, merchantUID string, req ListMerchantOrdersPayload) ([]*merchantOrderQueryResult, *response.Pagination, error) {
+	q := db.NewQuery(`
+		SELECT o.id, o.status, o.created_at, o.updated_at, oi.id, oi.items
+		FROM order_items oi
+		INNER JOIN orders o on oi.order_id = o.id
+	`)
+	q.Where("oi.merchant_id = ?", merchantUID)
+	if req.Status != "" {
+		q.Where("o.status = ?", req.Status)
+	}
+	if req.Cursor != nil {
+		after, err := response.DecodeCreatedAtCursor(*req.Cursor)
+		if err != nil {
+			return nil, nil, err
+		}
+		if after != nil {
+			q.Where("(o.created_at, oi.id) < (?, ?)", *after.CreatedAt, after.ID)
+		}
+	}
+	q.OrderBy("o.created_at DESC").OrderBy("oi.id DESC")
+	if req.Cursor != nil {
+		// one extra order tells whether there is a next page
+		q.Limit(req.Limit + 1)
+	} else {
+		q.Page(req.Limit, req.Offset)
+	}
+
+	query, args := q.Build()
+	rows, err := d.db.Executor(d.tx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	res := make([]*merchantOrderQueryResult, 0)
+	for rows.Next() {
+		o := &merchantOrderQueryResult{}
+		err = rows.Scan(&o.Order.ID, &o.Order.Status, &o.Order.CreatedAt, &o.Order.UpdatedAt, &o.OrderItemID, &o.Items)
+		if err != nil {
+			return nil, nil, err
+		}
+		res = append(res, o)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	pagination := &response.Pagination{Limit: req.Limit}
+	if req.Cursor == nil {
+		pagination.Offset = req.Offset
+	} else if len(res) > req.Limit {
+		res = res[:req.Limit]
+		last := res[len(res)-1]
+		pagination.NextCursor = response.Cursor{CreatedAt: &last.Order.CreatedAt, ID: last.OrderItemID}.Encode()
+	}
+	return res, pagination, nil
+}
+
+type merchantOrderQueryResult struct {
+	Order       Order
+	OrderItemID string // the merchant has one item row per order, which keys the cursor
+	Items       Items
+}
+
+// escapeLike escapes the LIKE wildcards in s, so it is matched literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
 }
@@ -5,6 +5,7 @@ import validation "github.com/go-ozzo/ozzo-validation/v4"
 type CalculateOrderEstimateRequest struct {
 	UserLocation UserLocationRequest `json:"userLocation"`
 	Orders       []OrderRequest      `json:"orders"`
+	VoucherCode  string              `json:"voucherCode"`
 }
 
 func (p CalculateOrderEstimateRequest) Validate() error {
@@ -41,8 +42,9 @@ func (p OrderRequest) Validate() error {
 }
 
 type OrderItemRequest struct {
-	ItemID   string `json:"itemId"`
-	Quantity int    `json:"quantity"`
+	ItemID   string   `json:"itemId"`
+	Quantity int      `json:"quantity"`
+	Options  []string `json:"options"` // selected option ids
 }
 
 func (p OrderItemRequest) Validate() error {
@@ -62,10 +64,36 @@ func (p CreateOrderRequest) Validate() error {
 	)
 }
 
+type UpdateOrderStatusRequest struct {
+	Status OrderStatus `json:"status"`
+}
+
+func (p UpdateOrderStatusRequest) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Status, validation.Required, validation.In(OrderStatuses...)),
+	)
+}
+
+type ListMerchantOrdersPayload struct {
+	Status OrderStatus `schema:"status" binding:"omitempty"`
+	Limit  int         `schema:"limit" binding:"omitempty"`
+	Offset int         `schema:"offset" binding:"omitempty"`
+	Cursor *string     `schema:"cursor" binding:"omitempty"` // set, even empty, to page by cursor
+}
+
+func (p ListMerchantOrdersPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Status, validation.In(OrderStatuses...)),
+		validation.Field(&p.Limit, validation.Min(0)),
+		validation.Field(&p.Offset, validation.Min(0)),
+	)
+}
+
 type SearchOrderPayload struct {
-	MerchantID       string `schema:"merchantId" binding:"omitempty"`
-	Name             string `schema:"name" binding:"omitempty"`
-	MerchantCategory string `schema:"merchantCategory" binding:"omitempty"`
-	Limit            int    `schema:"limit" binding:"omitempty"`
-	Offset           int    `schema:"offset" binding:"omitempty"`
+	MerchantID       string  `schema:"merchantId" binding:"omitempty"`
+	Name             string  `schema:"name" binding:"omitempty"`
+	MerchantCategory string  `schema:"merchantCategory" binding:"omitempty"`
+	Limit            int     `schema:"limit" binding:"omitempty"`
+	Offset           int     `schema:"offset" binding:"omitempty"`
+	Cursor           *string `schema:"cursor" binding:"omitempty"` // set, even empty, to page by cursor
 }
@@ -1,18 +1,54 @@
 package order
 
 import (
+	"time"
+
 	merchantitems "github.com/citadel-corp/belimang/internal/merchant_items"
 	"github.com/citadel-corp/belimang/internal/merchants"
 )
 
 type CalculateOrderEstimateResponse struct {
-	TotalPrice                     int    `json:"totalPrice"`
-	EstimatedDeliveryTimeInMinutes int    `json:"estimatedDeliveryTimeInMinutes"`
-	CalculatedEstimateID           string `json:"calculatedEstimateId"`
+	TotalPrice                     int            `json:"totalPrice"`
+	PriceBreakdown                 PriceBreakdown `json:"priceBreakdown"`
+	EstimatedDeliveryTimeInMinutes int            `json:"estimatedDeliveryTimeInMinutes"`
+	CalculatedEstimateID           string         `json:"calculatedEstimateId"`
+	ExpiresAt                      time.Time      `json:"expiresAt"`
+	Route                          EstimateRoute  `json:"route"`
 }
 
 type CreateOrderResponse struct {
-	OrderID string `json:"orderId"`
+	OrderID string        `json:"orderId"`
+	Route   EstimateRoute `json:"route"`
+}
+
+type OrderStatusResponse struct {
+	OrderID   string                       `json:"orderId"`
+	Status    OrderStatus                  `json:"status"`
+	UpdatedAt time.Time                    `json:"updatedAt"`
+	History   []OrderStatusHistoryResponse `json:"history"`
+}
+
+type OrderStatusHistoryResponse struct {
+	FromStatus *OrderStatus `json:"fromStatus"`
+	ToStatus   OrderStatus  `json:"toStatus"`
+	ChangedAt  time.Time    `json:"changedAt"`
+}
+
+func CreateOrderStatusResponse(order *Order, histories []*OrderStatusHistory) *OrderStatusResponse {
+	historyResponse := make([]OrderStatusHistoryResponse, 0)
+	for _, h := range histories {
+		historyResponse = append(historyResponse, OrderStatusHistoryResponse{
+			FromStatus: h.FromStatus,
+			ToStatus:   h.ToStatus,
+			ChangedAt:  h.CreatedAt,
+		})
+	}
+	return &OrderStatusResponse{
+		OrderID:   order.ID,
+		Status:    order.Status,
+		UpdatedAt: order.UpdatedAt,
+		History:   historyResponse,
+	}
 }
 
 type SearchOrderResponse struct {
@@ -27,5 +63,40 @@ type SearchOrderDetailResponse struct {
 
 type SearchOrderDetailItemResponse struct {
 	merchantitems.MerchantItemResponse
-	Quantity int `json:"quantity"`
+	Options   []ItemOption `json:"options,omitempty"`
+	Quantity  int          `json:"quantity"`
+	LineTotal int          `json:"lineTotal"`
+}
+
+type MerchantOrderResponse struct {
+	OrderID   string                          `json:"orderId"`
+	Status    OrderStatus                     `json:"status"`
+	CreatedAt time.Time                       `json:"createdAt"`
+	UpdatedAt time.Time                       `json:"updatedAt"`
+	Items     []SearchOrderDetailItemResponse `json:"items"`
+}
+
+func CreateMerchantOrderResponse(order *Order, items Items) *MerchantOrderResponse {
+	itemsResponse := make([]SearchOrderDetailItemResponse, 0, len(items))
+	for _, item := range items {
+		itemsResponse = append(itemsResponse, SearchOrderDetailItemResponse{
+			MerchantItemResponse: merchantitems.MerchantItemResponse{
+				UID:             item.ItemID,
+				Name:            item.Name,
+				ProductCategory: item.Category,
+				Price:           item.Price,
+				ImageURL:        item.ImageURL,
+			},
+			Options:   item.Options,
+			Quantity:  item.Quantity,
+			LineTotal: item.LineTotal,
+		})
+	}
+	return &MerchantOrderResponse{
+		OrderID:   order.ID,
+		Status:    order.Status,
+		CreatedAt: order.CreatedAt,
+		UpdatedAt: order.UpdatedAt,
+		Items:     itemsResponse,
+	}
 }
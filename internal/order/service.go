@@ -2,30 +2,49 @@ package order
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"slices"
+	"strings"
+	"time"
 
+	"github.com/citadel-corp/belimang/internal/common/db"
 	"github.com/citadel-corp/belimang/internal/common/haversine"
 	"github.com/citadel-corp/belimang/internal/common/id"
+	"github.com/citadel-corp/belimang/internal/common/permission"
+	"github.com/citadel-corp/belimang/internal/common/response"
 	merchantitems "github.com/citadel-corp/belimang/internal/merchant_items"
 	"github.com/citadel-corp/belimang/internal/merchants"
+	"github.com/citadel-corp/belimang/internal/promotions"
 )
 
 type Service interface {
 	CalculateEstimate(ctx context.Context, req CalculateOrderEstimateRequest, userID string) (*CalculateOrderEstimateResponse, error)
 	CreateOrder(ctx context.Context, req CreateOrderRequest, userID string) (*CreateOrderResponse, error)
-	SearchOrders(ctx context.Context, req SearchOrderPayload, userID string) ([]*SearchOrderResponse, error)
+	SearchOrders(ctx context.Context, req SearchOrderPayload, userID string) ([]*SearchOrderResponse, *response.Pagination, error)
+	GetOrderStatus(ctx context.Context, orderID string, userID string) (*OrderStatusResponse, error)
+	UpdateOrderStatus(ctx context.Context, orderID string, req UpdateOrderStatusRequest, changedBy string) (*OrderStatusResponse, error)
+	ListMerchantOrders(ctx context.Context, merchantUID string, req ListMerchantOrdersPayload) ([]*MerchantOrderResponse, *response.Pagination, error)
+	UpdateMerchantOrderStatus(ctx context.Context, merchantUID string, orderID string, req UpdateOrderStatusRequest, changedBy string) (*OrderStatusResponse, error)
 }
 
 type orderService struct {
+	transactor              db.Transactor
 	repository              Repository
+	pricing                 PricingConfig
+	delivery                haversine.DeliveryConfig
+	promotionsRepository    promotions.Repository
 	merchantRepository      merchants.Repository
 	merchantItemsRepository merchantitems.Repository
 }
 
-func NewService(repository Repository, merchantRepository merchants.Repository, merchantItemsRepository merchantitems.Repository) Service {
+func NewService(transactor db.Transactor, repository Repository, pricing PricingConfig, delivery haversine.DeliveryConfig, promotionsRepository promotions.Repository, merchantRepository merchants.Repository, merchantItemsRepository merchantitems.Repository) Service {
 	return &orderService{
+		transactor:              transactor,
 		repository:              repository,
+		pricing:                 pricing,
+		delivery:                delivery,
+		promotionsRepository:    promotionsRepository,
 		merchantRepository:      merchantRepository,
 		merchantItemsRepository: merchantItemsRepository,
 	}
@@ -41,8 +60,8 @@ func (s *orderService) CalculateEstimate(ctx context.Context, req CalculateOrder
 	startingPointCount := 0
 	merchantIDs := make([]string, len(req.Orders))
 	startingMerchantID := ""
-	allItems := make([]OrderItemRequest, 0)
 	calculateEstimateItems := make(Items, 0)
+	itemOptionIDs := make([][]string, 0)    // selected options of each calculated estimate item
 	itemQuantityMap := make(map[string]int) // key: item id
 	merchantItemIDs := make([]string, 0)
 	for i, order := range req.Orders {
@@ -52,7 +71,7 @@ func (s *orderService) CalculateEstimate(ctx context.Context, req CalculateOrder
 		}
 		merchantIDs[i] = order.MerchantID
 		for _, item := range order.Items {
-			allItems = append(allItems, item)
+			itemOptionIDs = append(itemOptionIDs, item.Options)
 			calculateEstimateItems = append(calculateEstimateItems, Item{
 				ItemID:     item.ItemID,
 				MerchantID: order.MerchantID,
@@ -76,6 +95,11 @@ func (s *orderService) CalculateEstimate(ctx context.Context, req CalculateOrder
 	if len(merchantIDs) != len(merchantList) {
 		return nil, ErrSomeMerchantNotFound
 	}
+	departure := time.Now()
+	err = s.checkMerchantsOpen(ctx, merchantList, departure)
+	if err != nil {
+		return nil, err
+	}
 	itemList, err := s.merchantItemsRepository.ListByUIDs(ctx, merchantItemIDs)
 	if err != nil {
 		return nil, err
@@ -83,28 +107,104 @@ func (s *orderService) CalculateEstimate(ctx context.Context, req CalculateOrder
 	if len(itemQuantityMap) != len(itemList) {
 		return nil, ErrSomeItemNotFound
 	}
-	totalPrice := 0
-	itemPriceMap := make(map[string]int)
+	unavailableItemIDs := make([]string, 0)
 	for _, item := range itemList {
-		itemPriceMap[item.UID] = item.Price
+		if !item.IsAvailable {
+			unavailableItemIDs = append(unavailableItemIDs, item.UID)
+		}
+	}
+	if len(unavailableItemIDs) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrSomeItemUnavailable, strings.Join(unavailableItemIDs, ", "))
 	}
-	for _, item := range allItems {
-		totalPrice += itemPriceMap[item.ItemID] * item.Quantity
+	itemMap := make(map[string]*merchantitems.MerchantItems) // key: item id
+	itemIDs := make([]uint64, 0, len(itemList))
+	for _, item := range itemList {
+		itemMap[item.UID] = item
+		itemIDs = append(itemIDs, item.ID)
+	}
+	// items are priced and discounted as their merchant's, whatever merchant they are listed under
+	for _, item := range calculateEstimateItems {
+		if itemMap[item.ItemID].MerchantUID != item.MerchantID {
+			return nil, fmt.Errorf("%w: %w: item %s, merchant %s", ErrValidationFailed, ErrItemMerchantMismatch, item.ItemID, item.MerchantID)
+		}
+	}
+	optionGroups, err := s.merchantItemsRepository.ListOptionGroupsByItemIDs(ctx, itemIDs)
+	if err != nil {
+		return nil, err
+	}
+	subtotal := 0
+	for i, item := range calculateEstimateItems {
+		merchantItem := itemMap[item.ItemID]
+		selectedOptions, err := merchantitems.SelectOptions(optionGroups[merchantItem.ID], itemOptionIDs[i])
+		if err != nil {
+			return nil, fmt.Errorf("item %s: %w", item.ItemID, err)
+		}
+		// the unit price includes the price deltas of the selected options
+		unitPrice := merchantItem.Price
+		options := make([]ItemOption, 0, len(selectedOptions))
+		for _, selected := range selectedOptions {
+			unitPrice += selected.Option.PriceDelta
+			options = append(options, ItemOption{
+				OptionID:   selected.Option.UID,
+				GroupName:  selected.Group.Name,
+				Name:       selected.Option.Name,
+				PriceDelta: selected.Option.PriceDelta,
+			})
+		}
+		calculateEstimateItems[i].Name = merchantItem.Name
+		calculateEstimateItems[i].Category = merchantItem.Category
+		calculateEstimateItems[i].Price = unitPrice
+		calculateEstimateItems[i].ImageURL = merchantItem.ImageURL
+		calculateEstimateItems[i].CreatedAt = merchantItem.CreatedAt
+		calculateEstimateItems[i].Options = options
+		calculateEstimateItems[i].LineTotal = unitPrice * item.Quantity
+		subtotal += calculateEstimateItems[i].LineTotal
+	}
+	var (
+		promotion *promotions.Promotions
+		discount  int
+	)
+	if req.VoucherCode != "" {
+		lines := make([]promotions.Line, 0, len(calculateEstimateItems))
+		for _, item := range calculateEstimateItems {
+			lines = append(lines, promotions.Line{
+				MerchantUID: itemMap[item.ItemID].MerchantUID,
+				Category:    item.Category,
+				Amount:      item.LineTotal,
+			})
+		}
+		promotion, discount, err = s.applyPromotion(ctx, req.VoucherCode, userID, lines)
+		if err != nil {
+			return nil, err
+		}
 	}
 	// calculate delivery time
-	deliveryTime, err := haversine.CalculateDeliveryTime(req.UserLocation.Lat, req.UserLocation.Long, startingMerchantID, merchantList)
+	route, deliveryTime, err := s.delivery.CalculateDeliveryTime(req.UserLocation.Lat, req.UserLocation.Long, startingMerchantID, merchantList, departure)
 	if err != nil {
 		return nil, err
 	}
+	estimateRoute := s.createEstimateRoute(route, req.UserLocation, departure)
+	priceBreakdown, err := s.pricing.Price(subtotal, discount, route.TotalDistance, merchantList)
+	if err != nil {
+		return nil, err
+	}
+	var promotionID *string
+	if promotion != nil {
+		priceBreakdown.VoucherCode = promotion.Code
+		promotionID = &promotion.UID
+	}
 	calculatedEstimate := &CalculatedEstimate{
 		ID:                    id.GenerateStringID(16),
 		UserID:                userID,
-		TotalPrice:            totalPrice,
+		TotalPrice:            priceBreakdown.Total,
+		PriceBreakdown:        priceBreakdown,
+		PromotionID:           promotionID,
 		Lat:                   req.UserLocation.Lat,
 		Long:                  req.UserLocation.Long,
 		Merchants:             CalculatedEstimateMerchants(merchantIDs),
 		Items:                 calculateEstimateItems,
 		EstimatedDeliveryTime: deliveryTime,
+		Route:                 estimateRoute,
 		Ordered:               false,
 	}
 	err = s.repository.InsertCalculatedEstimate(ctx, calculatedEstimate)
@@ -113,12 +213,87 @@ func (s *orderService) CalculateEstimate(ctx context.Context, req CalculateOrder
 	}
 
 	return &CalculateOrderEstimateResponse{
-		TotalPrice:                     totalPrice,
+		TotalPrice:                     priceBreakdown.Total,
+		PriceBreakdown:                 priceBreakdown,
 		EstimatedDeliveryTimeInMinutes: deliveryTime,
 		CalculatedEstimateID:           calculatedEstimate.ID,
+		ExpiresAt:                      calculatedEstimate.ExpiresAt,
+		Route:                          estimateRoute,
 	}, nil
 }
 
+// checkMerchantsOpen rejects the estimate when a merchant is closed at t, in its own time zone.
+func (s *orderService) checkMerchantsOpen(ctx context.Context, merchantList []*merchants.Merchants, t time.Time) error {
+	merchantIDs := make([]uint64, 0, len(merchantList))
+	for _, merchant := range merchantList {
+		merchantIDs = append(merchantIDs, merchant.ID)
+	}
+	schedules, err := s.merchantRepository.ListSchedules(ctx, merchantIDs)
+	if err != nil {
+		return err
+	}
+	for _, merchant := range merchantList {
+		schedule, ok := schedules[merchant.ID]
+		if !ok || schedule.IsOpen(t) {
+			continue
+		}
+		if opensAt := schedule.NextOpening(t); opensAt != nil {
+			return fmt.Errorf("%w: %s opens at %s", ErrMerchantClosed, merchant.UID, opensAt.Format(time.RFC3339))
+		}
+		return fmt.Errorf("%w: %s", ErrMerchantClosed, merchant.UID)
+	}
+	return nil
+}
+
+// applyPromotion looks up the voucher and computes its discount over the estimate lines.
+// Usage is only recorded once the estimate is ordered.
+func (s *orderService) applyPromotion(ctx context.Context, voucherCode string, userID string, lines []promotions.Line) (*promotions.Promotions, int, error) {
+	promotion, err := s.promotionsRepository.GetByCode(ctx, voucherCode)
+	if err != nil {
+		return nil, 0, err
+	}
+	usageCount, err := s.promotionsRepository.CountUsagesByUser(ctx, promotion.ID, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	err = promotion.CheckAvailability(time.Now().UTC(), usageCount)
+	if err != nil {
+		return nil, 0, err
+	}
+	discount, err := promotion.Discount(lines)
+	if err != nil {
+		return nil, 0, err
+	}
+	return promotion, discount, nil
+}
+
+// createEstimateRoute turns the planned route into stops carrying the leg leading to
+// them, ending with the leg to the user.
+func (s *orderService) createEstimateRoute(route *haversine.Route, userLocation UserLocationRequest, departure time.Time) EstimateRoute {
+	res := EstimateRoute{
+		Stops: make([]RouteStop, 0, len(route.Stops)),
+	}
+	legDistance, cumulativeDistance := 0.0, 0.0
+	for i, merchant := range route.Stops {
+		res.Stops = append(res.Stops, RouteStop{
+			MerchantID:          merchant.UID,
+			Lat:                 merchant.Lat,
+			Long:                merchant.Lng,
+			LegDistanceInMeters: int(legDistance * 1000),
+			CumulativeMinutes:   int(s.delivery.TravelMinutes(cumulativeDistance, departure)),
+		})
+		legDistance = route.Legs[i]
+		cumulativeDistance += legDistance
+	}
+	res.Destination = RouteStop{
+		Lat:                 userLocation.Lat,
+		Long:                userLocation.Long,
+		LegDistanceInMeters: int(legDistance * 1000),
+		CumulativeMinutes:   int(s.delivery.TravelMinutes(cumulativeDistance, departure)),
+	}
+	return res
+}
+
 // CreateOrder implements Service.
 func (s *orderService) CreateOrder(ctx context.Context, req CreateOrderRequest, userID string) (*CreateOrderResponse, error) {
 	err := req.Validate()
@@ -129,107 +304,299 @@ func (s *orderService) CreateOrder(ctx context.Context, req CreateOrderRequest,
 	if err != nil {
 		return nil, err
 	}
+	if calculatedEstimate.UserID != userID {
+		return nil, ErrCalculatedEstimateNotOwned
+	}
+	if calculatedEstimate.Ordered {
+		return nil, ErrCalculatedEstimateOrdered
+	}
 	order := &Order{
 		ID:                   id.GenerateStringID(16),
 		CalculatedEstimateID: calculatedEstimate.ID,
 		UserID:               userID,
-	}
-	err = s.repository.InsertOrder(ctx, order)
-	if err != nil {
-		return nil, err
+		Status:               Placed,
 	}
 	merchantItemMap := make(map[string][]Item) // key: merchant id
+	merchantIDs := make([]string, 0)
 	for _, item := range calculatedEstimate.Items {
+		if _, ok := merchantItemMap[item.MerchantID]; !ok {
+			merchantIDs = append(merchantIDs, item.MerchantID)
+		}
+		item.LineTotal = item.Price * item.Quantity
 		merchantItemMap[item.MerchantID] = append(merchantItemMap[item.MerchantID], item)
 	}
-	for k, v := range merchantItemMap {
-		err = s.repository.InsertOrderItem(ctx, &OrderItem{
+	// merchants are snapshotted with the order so later changes do not rewrite its history
+	merchantList, err := s.merchantRepository.ListByUIDs(ctx, merchantIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(merchantList) != len(merchantIDs) {
+		return nil, ErrSomeMerchantNotFound
+	}
+	merchantMap := make(map[string]*merchants.Merchants) // key: merchant id
+	for _, merchant := range merchantList {
+		merchantMap[merchant.UID] = merchant
+	}
+	orderItems := make([]*OrderItem, 0, len(merchantIDs))
+	for _, merchantID := range merchantIDs {
+		merchant := merchantMap[merchantID]
+		orderItems = append(orderItems, &OrderItem{
 			ID:         id.GenerateStringID(16),
 			OrderID:    order.ID,
-			MerchantID: k,
-			Items:      v,
+			MerchantID: merchantID,
+			Items:      merchantItemMap[merchantID],
+			Merchant: MerchantSnapshot{
+				MerchantID: merchant.UID,
+				Name:       merchant.Name,
+				Category:   merchant.Category,
+				ImageURL:   merchant.ImageURL,
+				Lat:        merchant.Lat,
+				Long:       merchant.Lng,
+				CreatedAt:  merchant.CreatedAt,
+			},
 		})
+	}
+	// the estimate claim, the order and its items are committed or rolled back together
+	err = s.transactor.StartTx(ctx, func(tx *sql.Tx) error {
+		repository := s.repository.WithTx(tx)
+		// expiry and double ordering are re-checked atomically while claiming the estimate
+		err := repository.ClaimCalculatedEstimate(ctx, calculatedEstimate.ID, userID)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		err = repository.InsertOrder(ctx, order)
+		if err != nil {
+			return err
+		}
+		err = repository.InsertOrderStatusHistory(ctx, &OrderStatusHistory{
+			OrderID:   order.ID,
+			ToStatus:  order.Status,
+			ChangedBy: userID,
+		})
+		if err != nil {
+			return err
 		}
+		for _, orderItem := range orderItems {
+			err = repository.InsertOrderItem(ctx, orderItem)
+			if err != nil {
+				return err
+			}
+		}
+		if calculatedEstimate.PromotionID == nil {
+			return nil
+		}
+		// the promotion row stays locked until commit so concurrent orders cannot
+		// exceed its usage limits
+		promotionsRepository := s.promotionsRepository.WithTx(tx)
+		promotion, err := promotionsRepository.GetByUIDForUpdate(ctx, *calculatedEstimate.PromotionID)
+		if err != nil {
+			return err
+		}
+		usageCount, err := promotionsRepository.CountUsagesByUser(ctx, promotion.ID, userID)
+		if err != nil {
+			return err
+		}
+		err = promotion.CheckAvailability(time.Now().UTC(), usageCount)
+		if err != nil {
+			return err
+		}
+		return promotionsRepository.InsertUsage(ctx, &promotions.PromotionUsages{
+			PromotionID: promotion.ID,
+			UserID:      userID,
+			OrderID:     order.ID,
+			Discount:    calculatedEstimate.PriceBreakdown.Discount,
+		})
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &CreateOrderResponse{
 		OrderID: order.ID,
+		Route:   calculatedEstimate.Route,
 	}, nil
 }
 
 // SearchOrders implements Service.
-func (s *orderService) SearchOrders(ctx context.Context, req SearchOrderPayload, userID string) ([]*SearchOrderResponse, error) {
+func (s *orderService) SearchOrders(ctx context.Context, req SearchOrderPayload, userID string) ([]*SearchOrderResponse, *response.Pagination, error) {
 	if req.Limit == 0 {
 		req.Limit = 5
 	}
-	orderItemMerchants, err := s.repository.SearchOrderItemMerchants(ctx, req, userID)
+	orderItemMerchants, pagination, err := s.repository.SearchOrderItemMerchants(ctx, req, userID)
 	if err != nil {
-		return nil, err
-	}
-	actualMerchantIDs := make([]string, 0)
-	for _, orderItemMerchant := range orderItemMerchants {
-		actualMerchantIDs = append(actualMerchantIDs, orderItemMerchant.MerchantID)
-	}
-	// searchResults, err := s.repository.SearchOrder(ctx, req, userID)\
-	orderItemDetailsMap := make(map[string]Items) // key: orderID-merchantID
-	for _, orderItemMerchant := range orderItemMerchants {
-		key := fmt.Sprintf("%s-%s", orderItemMerchant.OrderID, orderItemMerchant.MerchantID)
-		for _, item := range orderItemMerchant.OrderItems {
-			orderItemDetailsMap[key] = append(orderItemDetailsMap[key], item)
-		}
-	}
-	items, err := s.merchantItemsRepository.ListByMerchantUIDAndName(ctx, actualMerchantIDs, req.Name)
-	if err != nil {
-		return nil, err
-	}
-	itemsMap := make(map[string]*merchantitems.MerchantItems) // key: itemID
-	for _, item := range items {
-		itemsMap[item.UID] = item
+		return nil, nil, err
 	}
 
+	orderIDs := make([]string, 0)
 	orderItemMerchantsMap := make(map[string][]SearchOrderDetailResponse) // key: orderID
-	res := make([]*SearchOrderResponse, 0)
 	for _, orderItemMerchant := range orderItemMerchants {
 		searchOrderDetailItemResponse := make([]SearchOrderDetailItemResponse, 0)
-		key := fmt.Sprintf("%s-%s", orderItemMerchant.OrderID, orderItemMerchant.MerchantID)
-		searchOrderDetailItems := orderItemDetailsMap[key]
-		for _, searchOrderDetailItem := range searchOrderDetailItems {
-			if item, ok := itemsMap[searchOrderDetailItem.ItemID]; ok {
-				searchOrderDetailItemResponse = append(searchOrderDetailItemResponse, SearchOrderDetailItemResponse{
-					MerchantItemResponse: merchantitems.MerchantItemResponse{
-						UID:             item.UID,
-						Name:            item.Name,
-						ProductCategory: item.Category,
-						Price:           item.Price,
-						ImageURL:        item.ImageURL,
-						CreatedAt:       item.CreatedAt.Nanosecond(),
-					},
-					Quantity: searchOrderDetailItem.Quantity,
-				})
+		for _, item := range orderItemMerchant.OrderItems {
+			if req.Name != "" && !strings.Contains(strings.ToLower(item.Name), strings.ToLower(req.Name)) {
+				continue
 			}
+			searchOrderDetailItemResponse = append(searchOrderDetailItemResponse, SearchOrderDetailItemResponse{
+				MerchantItemResponse: merchantitems.MerchantItemResponse{
+					UID:             item.ItemID,
+					Name:            item.Name,
+					ProductCategory: item.Category,
+					Price:           item.Price,
+					ImageURL:        item.ImageURL,
+					CreatedAt:       item.CreatedAt.Nanosecond(),
+				},
+				Options:   item.Options,
+				Quantity:  item.Quantity,
+				LineTotal: item.LineTotal,
+			})
 		}
+		if _, ok := orderItemMerchantsMap[orderItemMerchant.OrderID]; !ok {
+			orderIDs = append(orderIDs, orderItemMerchant.OrderID)
+		}
+		merchant := orderItemMerchant.Merchant
 		orderItemMerchantsMap[orderItemMerchant.OrderID] = append(orderItemMerchantsMap[orderItemMerchant.OrderID], SearchOrderDetailResponse{
 			Merchant: merchants.MerchantsResponse{
-				UID:      orderItemMerchant.MerchantID,
-				Name:     orderItemMerchant.MerchantName,
-				Category: orderItemMerchant.MerchantCategory,
-				ImageURL: orderItemMerchant.MerchantImageURL,
+				UID:      merchant.MerchantID,
+				Name:     merchant.Name,
+				Category: string(merchant.Category),
+				ImageURL: merchant.ImageURL,
 				Location: merchants.LocationResponse{
-					Lat: orderItemMerchant.MerchantLat,
-					Lng: orderItemMerchant.MerchantLong,
+					Lat: merchant.Lat,
+					Lng: merchant.Long,
 				},
-				CreatedAt: orderItemMerchant.MerchantCreatedAt.Nanosecond(),
+				CreatedAt: merchant.CreatedAt.Nanosecond(),
 			},
 			Items: searchOrderDetailItemResponse,
 		})
 	}
-	for k, v := range orderItemMerchantsMap {
+	res := make([]*SearchOrderResponse, 0, len(orderIDs))
+	for _, orderID := range orderIDs {
 		res = append(res, &SearchOrderResponse{
-			OrderID: k,
-			Orders:  v,
+			OrderID: orderID,
+			Orders:  orderItemMerchantsMap[orderID],
 		})
 	}
-	return res, nil
+	return res, pagination, nil
+}
+
+// GetOrderStatus implements Service.
+func (s *orderService) GetOrderStatus(ctx context.Context, orderID string, userID string) (*OrderStatusResponse, error) {
+	order, err := s.repository.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	// do not leak the existence of other users' orders
+	if order.UserID != userID {
+		return nil, ErrOrderNotFound
+	}
+	histories, err := s.repository.ListOrderStatusHistories(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	return CreateOrderStatusResponse(order, histories), nil
+}
+
+// UpdateOrderStatus implements Service.
+func (s *orderService) UpdateOrderStatus(ctx context.Context, orderID string, req UpdateOrderStatusRequest, changedBy string) (*OrderStatusResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	order, err := s.repository.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !order.Status.CanTransitionTo(req.Status) {
+		return nil, fmt.Errorf("%w: cannot move order from %s to %s", ErrInvalidStatusTransition, order.Status, req.Status)
+	}
+	fromStatus := order.Status
+	history := &OrderStatusHistory{
+		OrderID:    order.ID,
+		FromStatus: &fromStatus,
+		ToStatus:   req.Status,
+		ChangedBy:  changedBy,
+	}
+	err = s.transactor.StartTx(ctx, func(tx *sql.Tx) error {
+		repository := s.repository.WithTx(tx)
+		err := repository.UpdateOrderStatus(ctx, history)
+		if err != nil {
+			return err
+		}
+		return repository.InsertOrderStatusHistory(ctx, history)
+	})
+	if err != nil {
+		return nil, err
+	}
+	order, err = s.repository.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	histories, err := s.repository.ListOrderStatusHistories(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	return CreateOrderStatusResponse(order, histories), nil
+}
+
+// merchantOrderStatuses are the statuses merchant staff may move the orders of
+// their merchant to, the rest belongs to couriers and customers.
+var merchantOrderStatuses = []OrderStatus{Accepted, Rejected, Preparing}
+
+// ListMerchantOrders implements Service.
+func (s *orderService) ListMerchantOrders(ctx context.Context, merchantUID string, req ListMerchantOrdersPayload) ([]*MerchantOrderResponse, *response.Pagination, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	if req.Limit == 0 {
+		req.Limit = 5
+	}
+	merchant, err := s.merchantRepository.GetByUID(ctx, merchantUID)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = merchants.Authorize(ctx, s.merchantRepository, merchant, permission.Read)
+	if err != nil {
+		return nil, nil, err
+	}
+	orders, pagination, err := s.repository.ListMerchantOrders(ctx, merchant.UID, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	res := make([]*MerchantOrderResponse, 0, len(orders))
+	for _, o := range orders {
+		res = append(res, CreateMerchantOrderResponse(&o.Order, o.Items))
+	}
+	return res, pagination, nil
+}
+
+// UpdateMerchantOrderStatus implements Service.
+// Staff of the merchant accept, reject and prepare the orders with its items.
+func (s *orderService) UpdateMerchantOrderStatus(ctx context.Context, merchantUID string, orderID string, req UpdateOrderStatusRequest, changedBy string) (*OrderStatusResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	if !slices.Contains(merchantOrderStatuses, req.Status) {
+		return nil, fmt.Errorf("%w: status: merchants cannot move orders to %s", ErrValidationFailed, req.Status)
+	}
+	merchant, err := s.merchantRepository.GetByUID(ctx, merchantUID)
+	if err != nil {
+		return nil, err
+	}
+	err = merchants.Authorize(ctx, s.merchantRepository, merchant, permission.Write)
+	if err != nil {
+		return nil, err
+	}
+	orderItems, err := s.repository.ListOrderItemsByOrderID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	hasMerchant := slices.ContainsFunc(orderItems, func(orderItem *OrderItem) bool {
+		return orderItem.MerchantID == merchant.UID
+	})
+	if !hasMerchant {
+		// orders of other merchants are not theirs to see
+		return nil, ErrOrderNotFound
+	}
+	return s.UpdateOrderStatus(ctx, orderID, req, changedBy)
 }
@@ -0,0 +1,209 @@
+package order
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/citadel-corp/belimang/internal/merchants"
+)
+
+var errInsertFailed = errors.New("insert failed")
+
+// orderState is what the fake repository persists.
+type orderState struct {
+	claimed    []string // ids of the claimed estimates
+	orders     []*Order
+	histories  []*OrderStatusHistory
+	orderItems []*OrderItem
+}
+
+func (s orderState) clone() orderState {
+	return orderState{
+		claimed:    slices.Clone(s.claimed),
+		orders:     slices.Clone(s.orders),
+		histories:  slices.Clone(s.histories),
+		orderItems: slices.Clone(s.orderItems),
+	}
+}
+
+// fakeDB keeps the committed state, and the state of the transaction in progress
+// until the fakeTransactor commits or rolls it back.
+type fakeDB struct {
+	committed orderState
+	tx        *orderState
+	commits   int
+	rollbacks int
+}
+
+func (d *fakeDB) state() *orderState {
+	if d.tx != nil {
+		return d.tx
+	}
+	return &d.committed
+}
+
+type fakeTransactor struct {
+	db *fakeDB
+}
+
+func (t *fakeTransactor) StartTx(ctx context.Context, f func(*sql.Tx) error) error {
+	tx := t.db.committed.clone()
+	t.db.tx = &tx
+	defer func() { t.db.tx = nil }()
+	if err := f(nil); err != nil {
+		t.db.rollbacks++
+		return err
+	}
+	t.db.committed = tx
+	t.db.commits++
+	return nil
+}
+
+// fakeRepository writes to the fakeDB, failing the failItemInsert-th order item
+// insert when it is set.
+type fakeRepository struct {
+	Repository
+	db              *fakeDB
+	estimate        *CalculatedEstimate
+	failItemInsert  int
+	itemInsertCount int
+}
+
+func (r *fakeRepository) WithTx(tx *sql.Tx) Repository {
+	return r
+}
+
+func (r *fakeRepository) GetCalculatedEstimate(ctx context.Context, id string) (*CalculatedEstimate, error) {
+	if r.estimate == nil || r.estimate.ID != id {
+		return nil, ErrCalculatedEstimateNotFound
+	}
+	estimate := *r.estimate
+	estimate.Ordered = slices.Contains(r.db.state().claimed, id)
+	return &estimate, nil
+}
+
+func (r *fakeRepository) ClaimCalculatedEstimate(ctx context.Context, id string, userID string) error {
+	state := r.db.state()
+	if slices.Contains(state.claimed, id) {
+		return ErrCalculatedEstimateOrdered
+	}
+	state.claimed = append(state.claimed, id)
+	return nil
+}
+
+func (r *fakeRepository) InsertOrder(ctx context.Context, order *Order) error {
+	state := r.db.state()
+	state.orders = append(state.orders, order)
+	return nil
+}
+
+func (r *fakeRepository) InsertOrderStatusHistory(ctx context.Context, history *OrderStatusHistory) error {
+	state := r.db.state()
+	state.histories = append(state.histories, history)
+	return nil
+}
+
+func (r *fakeRepository) InsertOrderItem(ctx context.Context, orderItem *OrderItem) error {
+	r.itemInsertCount++
+	if r.itemInsertCount == r.failItemInsert {
+		return errInsertFailed
+	}
+	state := r.db.state()
+	state.orderItems = append(state.orderItems, orderItem)
+	return nil
+}
+
+type fakeMerchantRepository struct {
+	merchants.Repository
+	merchants []*merchants.Merchants
+}
+
+func (r *fakeMerchantRepository) ListByUIDs(ctx context.Context, uids []string) ([]*merchants.Merchants, error) {
+	res := make([]*merchants.Merchants, 0)
+	for _, m := range r.merchants {
+		if slices.Contains(uids, m.UID) {
+			res = append(res, m)
+		}
+	}
+	return res, nil
+}
+
+func TestCreateOrderRollsBackOnFailedItemInsert(t *testing.T) {
+	const userID = "user0000000000001"
+	merchantList := make([]*merchants.Merchants, 0)
+	items := make(Items, 0)
+	for i := range 3 {
+		uid := fmt.Sprintf("merchant%08d", i)
+		merchantList = append(merchantList, &merchants.Merchants{ID: uint64(i + 1), UID: uid, Name: uid})
+		items = append(items, Item{ItemID: fmt.Sprintf("item%012d", i), MerchantID: uid, Quantity: 1})
+	}
+
+	tests := []struct {
+		name           string
+		failItemInsert int // zero never fails
+		wantErr        error
+	}{
+		{name: "all inserts succeed"},
+		{name: "first item insert fails", failItemInsert: 1, wantErr: errInsertFailed},
+		{name: "second item insert fails", failItemInsert: 2, wantErr: errInsertFailed},
+		{name: "last item insert fails", failItemInsert: 3, wantErr: errInsertFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeDB{}
+			repository := &fakeRepository{
+				db:             db,
+				estimate:       &CalculatedEstimate{ID: "estimate00000001", UserID: userID, Items: items},
+				failItemInsert: tt.failItemInsert,
+			}
+			service := &orderService{
+				transactor:         &fakeTransactor{db: db},
+				repository:         repository,
+				merchantRepository: &fakeMerchantRepository{merchants: merchantList},
+			}
+
+			res, err := service.CreateOrder(context.Background(), CreateOrderRequest{CalculatedEstimateID: "estimate00000001"}, userID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateOrder() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				if res != nil {
+					t.Errorf("CreateOrder() = %+v, want nil", res)
+				}
+				if db.commits != 0 || db.rollbacks != 1 {
+					t.Errorf("commits = %d, rollbacks = %d, want 0 and 1", db.commits, db.rollbacks)
+				}
+				got := db.committed
+				if len(got.claimed) != 0 || len(got.orders) != 0 || len(got.histories) != 0 || len(got.orderItems) != 0 {
+					t.Errorf("persisted %d claims, %d orders, %d histories, %d order items, want none",
+						len(got.claimed), len(got.orders), len(got.histories), len(got.orderItems))
+				}
+				estimate, err := repository.GetCalculatedEstimate(context.Background(), "estimate00000001")
+				if err != nil {
+					t.Fatalf("GetCalculatedEstimate() error = %v", err)
+				}
+				if estimate.Ordered {
+					t.Error("estimate claimed after rollback")
+				}
+				return
+			}
+
+			got := db.committed
+			if db.commits != 1 || db.rollbacks != 0 {
+				t.Errorf("commits = %d, rollbacks = %d, want 1 and 0", db.commits, db.rollbacks)
+			}
+			if len(got.claimed) != 1 || len(got.orders) != 1 || len(got.histories) != 1 || len(got.orderItems) != len(merchantList) {
+				t.Errorf("persisted %d claims, %d orders, %d histories, %d order items, want 1, 1, 1 and %d",
+					len(got.claimed), len(got.orders), len(got.histories), len(got.orderItems), len(merchantList))
+			}
+			if res == nil || len(got.orders) == 0 || res.OrderID != got.orders[0].ID {
+				t.Errorf("CreateOrder() = %+v, want the persisted order", res)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package promotions
+
+import "errors"
+
+var (
+	ErrPromotionNotFound          = errors.New("promotion not found")
+	ErrCodeAlreadyExists          = errors.New("promotion code already exists")
+	ErrValidationFailed           = errors.New("validation failed")
+	ErrPromotionNotActive         = errors.New("promotion is not active")
+	ErrPromotionUsageLimitReached = errors.New("promotion usage limit reached")
+	ErrPromotionUserLimitReached  = errors.New("promotion usage limit per user reached")
+	ErrPromotionNotApplicable     = errors.New("promotion does not apply to any ordered item")
+	ErrMinSpendNotMet             = errors.New("promotion minimum spend not met")
+)
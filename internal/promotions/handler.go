@@ -0,0 +1,165 @@
+package promotions
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/citadel-corp/belimang/internal/common/request"
+	"github.com/citadel-corp/belimang/internal/common/response"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/schema"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	var req CreatePromotionPayload
+
+	err := request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	promotionResp, err := h.service.Create(r.Context(), req)
+	if errors.Is(err, ErrValidationFailed) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrCodeAlreadyExists) {
+		response.JSON(w, http.StatusConflict, response.ResponseBody{
+			Message: "Promotion code already exists",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusCreated, promotionResp)
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	var req ListPromotionsPayload
+
+	newSchema := schema.NewDecoder()
+	newSchema.IgnoreUnknownKeys(true)
+
+	if err := newSchema.Decode(&req, r.URL.Query()); err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{})
+		return
+	}
+
+	promotionsResp, pagination, err := h.service.List(r.Context(), req)
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Data: promotionsResp,
+		Meta: pagination,
+	})
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	promotionResp, err := h.service.GetByUID(r.Context(), mux.Vars(r)["promotionId"])
+	if errors.Is(err, ErrPromotionNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Promotion not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, promotionResp)
+}
+
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	var req UpdatePromotionPayload
+
+	err := request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	promotionResp, err := h.service.Update(r.Context(), mux.Vars(r)["promotionId"], req)
+	if errors.Is(err, ErrValidationFailed) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrPromotionNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Promotion not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrCodeAlreadyExists) {
+		response.JSON(w, http.StatusConflict, response.ResponseBody{
+			Message: "Promotion code already exists",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, promotionResp)
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	err := h.service.Delete(r.Context(), mux.Vars(r)["promotionId"])
+	if errors.Is(err, ErrPromotionNotFound) {
+		response.JSON(w, http.StatusNotFound, response.ResponseBody{
+			Message: "Promotion not found",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "Promotion deleted successfully",
+	})
+}
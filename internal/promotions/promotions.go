@@ -0,0 +1,155 @@
+package promotions
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	merchantitems "github.com/citadel-corp/belimang/internal/merchant_items"
+)
+
+var (
+	MinCode = 3
+	MaxCode = 30
+)
+
+type DiscountType string
+
+var (
+	Percentage DiscountType = "Percentage"
+	Flat       DiscountType = "Flat"
+)
+
+var DiscountTypes = []interface{}{Percentage, Flat}
+
+type Promotions struct {
+	ID                uint64
+	UID               string
+	Code              string
+	DiscountType      DiscountType
+	DiscountValue     int
+	MaxDiscount       *int
+	MerchantUIDs      MerchantUIDs
+	ItemCategories    ItemCategories
+	MinSpend          int
+	UsageLimit        *int
+	UsageLimitPerUser *int
+	UsageCount        int
+	StartsAt          time.Time
+	EndsAt            *time.Time
+	CreatedAt         time.Time
+}
+
+type PromotionUsages struct {
+	ID          uint64
+	PromotionID uint64
+	UserID      string
+	OrderID     string
+	Discount    int
+	CreatedAt   time.Time
+}
+
+// Line is a priced line of an order the promotion may apply to.
+type Line struct {
+	MerchantUID string
+	Category    merchantitems.ItemCategory
+	Amount      int
+}
+
+// CheckAvailability reports whether the promotion can still be used at now by a user
+// who has already used it userUsageCount times.
+func (p *Promotions) CheckAvailability(now time.Time, userUsageCount int) error {
+	if now.Before(p.StartsAt) || (p.EndsAt != nil && !now.Before(*p.EndsAt)) {
+		return ErrPromotionNotActive
+	}
+	if p.UsageLimit != nil && p.UsageCount >= *p.UsageLimit {
+		return ErrPromotionUsageLimitReached
+	}
+	if p.UsageLimitPerUser != nil && userUsageCount >= *p.UsageLimitPerUser {
+		return ErrPromotionUserLimitReached
+	}
+	return nil
+}
+
+// Discount computes the discount for the lines falling in the promotion scope.
+func (p *Promotions) Discount(lines []Line) (int, error) {
+	eligible := 0
+	for _, line := range lines {
+		if len(p.MerchantUIDs) > 0 && !slices.Contains(p.MerchantUIDs, line.MerchantUID) {
+			continue
+		}
+		if len(p.ItemCategories) > 0 && !slices.Contains(p.ItemCategories, line.Category) {
+			continue
+		}
+		eligible += line.Amount
+	}
+	if eligible == 0 {
+		return 0, ErrPromotionNotApplicable
+	}
+	if eligible < p.MinSpend {
+		return 0, fmt.Errorf("%w: spend %d more to use this promotion", ErrMinSpendNotMet, p.MinSpend-eligible)
+	}
+
+	var discount int
+	switch p.DiscountType {
+	case Percentage:
+		discount = int(math.Floor(float64(eligible) * float64(p.DiscountValue) / 100))
+		if p.MaxDiscount != nil && discount > *p.MaxDiscount {
+			discount = *p.MaxDiscount
+		}
+	case Flat:
+		discount = p.DiscountValue
+	}
+	if discount > eligible {
+		discount = eligible
+	}
+	return discount, nil
+}
+
+type MerchantUIDs []string
+
+type ItemCategories []merchantitems.ItemCategory
+
+// Make the Attrs struct implement the driver.Valuer interface. This method
+// simply returns the JSON-encoded representation of the struct.
+func (a MerchantUIDs) Value() (driver.Value, error) {
+	if a == nil {
+		a = MerchantUIDs{}
+	}
+	return json.Marshal(a)
+}
+
+// Make the Attrs struct implement the sql.Scanner interface. This method
+// simply decodes a JSON-encoded value into the struct fields.
+func (a *MerchantUIDs) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(b, &a)
+}
+
+// Make the Attrs struct implement the driver.Valuer interface. This method
+// simply returns the JSON-encoded representation of the struct.
+func (a ItemCategories) Value() (driver.Value, error) {
+	if a == nil {
+		a = ItemCategories{}
+	}
+	return json.Marshal(a)
+}
+
+// Make the Attrs struct implement the sql.Scanner interface. This method
+// simply decodes a JSON-encoded value into the struct fields.
+func (a *ItemCategories) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(b, &a)
+}
@@ -0,0 +1,228 @@
+package promotions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/citadel-corp/belimang/internal/common/db"
+	"github.com/citadel-corp/belimang/internal/common/response"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type Repository interface {
+	WithTx(tx *sql.Tx) Repository
+	Create(ctx context.Context, promotion *Promotions) (err error)
+	List(ctx context.Context, filter ListPromotionsPayload) (promotions []Promotions, pagination *response.Pagination, err error)
+	GetByUID(ctx context.Context, uid string) (promotion *Promotions, err error)
+	GetByCode(ctx context.Context, code string) (promotion *Promotions, err error)
+	GetByUIDForUpdate(ctx context.Context, uid string) (promotion *Promotions, err error)
+	Update(ctx context.Context, promotion *Promotions) (err error)
+	Delete(ctx context.Context, uid string) (err error)
+	CountUsagesByUser(ctx context.Context, promotionID uint64, userID string) (count int, err error)
+	InsertUsage(ctx context.Context, usage *PromotionUsages) (err error)
+}
+
+type dbRepository struct {
+	db *db.DB
+	tx *sql.Tx
+}
+
+func NewRepository(db *db.DB) Repository {
+	return &dbRepository{db: db}
+}
+
+// WithTx implements Repository.
+func (d *dbRepository) WithTx(tx *sql.Tx) Repository {
+	return &dbRepository{db: d.db, tx: tx}
+}
+
+const promotionColumns = `id, uid, code, discount_type, discount_value, max_discount, merchant_uids, item_categories,
+	min_spend, usage_limit, usage_limit_per_user, usage_count, starts_at, ends_at, created_at`
+
+// Create implements Repository.
+func (d *dbRepository) Create(ctx context.Context, promotion *Promotions) (err error) {
+	q := `
+		INSERT INTO promotions (
+			uid, code, discount_type, discount_value, max_discount, merchant_uids, item_categories,
+			min_spend, usage_limit, usage_limit_per_user, starts_at, ends_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+		)
+		RETURNING id, created_at;
+	`
+	row := d.db.Executor(d.tx).QueryRowContext(ctx, q, promotion.UID, promotion.Code, promotion.DiscountType, promotion.DiscountValue,
+		promotion.MaxDiscount, promotion.MerchantUIDs, promotion.ItemCategories, promotion.MinSpend, promotion.UsageLimit,
+		promotion.UsageLimitPerUser, promotion.StartsAt, promotion.EndsAt)
+	err = row.Scan(&promotion.ID, &promotion.CreatedAt)
+	return uniqueCodeError(err)
+}
+
+// List implements Repository.
+func (d *dbRepository) List(ctx context.Context, filter ListPromotionsPayload) (promotions []Promotions, pagination *response.Pagination, err error) {
+	promotions = make([]Promotions, 0)
+
+	q := db.NewQuery(fmt.Sprintf(`
+		SELECT COUNT(*) OVER() AS total_count, %s
+		FROM promotions
+	`, promotionColumns))
+	q.Where("deleted_at IS NULL")
+	if filter.Code != "" {
+		q.Where("code = UPPER(?)", filter.Code)
+	}
+	q.OrderBy("created_at DESC")
+	q.Page(filter.Limit, filter.Offset)
+
+	query, args := q.Build()
+	rows, err := d.db.Executor(d.tx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	pagination = &response.Pagination{}
+	pagination.Limit = filter.Limit
+	pagination.Offset = filter.Offset
+
+	for rows.Next() {
+		p := Promotions{}
+		err = rows.Scan(&pagination.Total, &p.ID, &p.UID, &p.Code, &p.DiscountType, &p.DiscountValue, &p.MaxDiscount, &p.MerchantUIDs,
+			&p.ItemCategories, &p.MinSpend, &p.UsageLimit, &p.UsageLimitPerUser, &p.UsageCount, &p.StartsAt, &p.EndsAt, &p.CreatedAt)
+		if err != nil {
+			return
+		}
+		promotions = append(promotions, p)
+	}
+	return
+}
+
+// GetByUID implements Repository.
+func (d *dbRepository) GetByUID(ctx context.Context, uid string) (promotion *Promotions, err error) {
+	q := fmt.Sprintf(`
+		SELECT %s
+		FROM promotions
+		WHERE uid = $1 AND deleted_at IS NULL;
+	`, promotionColumns)
+	return d.get(ctx, q, uid)
+}
+
+// GetByCode implements Repository.
+func (d *dbRepository) GetByCode(ctx context.Context, code string) (promotion *Promotions, err error) {
+	q := fmt.Sprintf(`
+		SELECT %s
+		FROM promotions
+		WHERE code = UPPER($1) AND deleted_at IS NULL;
+	`, promotionColumns)
+	return d.get(ctx, q, code)
+}
+
+// GetByUIDForUpdate implements Repository.
+// The promotion row stays locked until the surrounding transaction ends, so usage
+// limits are checked and consumed atomically.
+func (d *dbRepository) GetByUIDForUpdate(ctx context.Context, uid string) (promotion *Promotions, err error) {
+	q := fmt.Sprintf(`
+		SELECT %s
+		FROM promotions
+		WHERE uid = $1 AND deleted_at IS NULL
+		FOR UPDATE;
+	`, promotionColumns)
+	return d.get(ctx, q, uid)
+}
+
+func (d *dbRepository) get(ctx context.Context, q string, args ...any) (promotion *Promotions, err error) {
+	row := d.db.Executor(d.tx).QueryRowContext(ctx, q, args...)
+	p := &Promotions{}
+	err = row.Scan(&p.ID, &p.UID, &p.Code, &p.DiscountType, &p.DiscountValue, &p.MaxDiscount, &p.MerchantUIDs,
+		&p.ItemCategories, &p.MinSpend, &p.UsageLimit, &p.UsageLimitPerUser, &p.UsageCount, &p.StartsAt, &p.EndsAt, &p.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPromotionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Update implements Repository.
+func (d *dbRepository) Update(ctx context.Context, promotion *Promotions) (err error) {
+	q := `
+		UPDATE promotions
+		SET code = $1, discount_type = $2, discount_value = $3, max_discount = $4, merchant_uids = $5, item_categories = $6,
+			min_spend = $7, usage_limit = $8, usage_limit_per_user = $9, starts_at = $10, ends_at = $11
+		WHERE uid = $12 AND deleted_at IS NULL;
+	`
+	res, err := d.db.Executor(d.tx).ExecContext(ctx, q, promotion.Code, promotion.DiscountType, promotion.DiscountValue,
+		promotion.MaxDiscount, promotion.MerchantUIDs, promotion.ItemCategories, promotion.MinSpend, promotion.UsageLimit,
+		promotion.UsageLimitPerUser, promotion.StartsAt, promotion.EndsAt, promotion.UID)
+	if err != nil {
+		return uniqueCodeError(err)
+	}
+	return expectOneRow(res)
+}
+
+// Delete implements Repository.
+func (d *dbRepository) Delete(ctx context.Context, uid string) (err error) {
+	q := `
+		UPDATE promotions
+		SET deleted_at = current_timestamp
+		WHERE uid = $1 AND deleted_at IS NULL;
+	`
+	res, err := d.db.Executor(d.tx).ExecContext(ctx, q, uid)
+	if err != nil {
+		return
+	}
+	return expectOneRow(res)
+}
+
+// CountUsagesByUser implements Repository.
+func (d *dbRepository) CountUsagesByUser(ctx context.Context, promotionID uint64, userID string) (count int, err error) {
+	q := `
+		SELECT COUNT(*)
+		FROM promotion_usages
+		WHERE promotion_id = $1 AND user_id = $2;
+	`
+	err = d.db.Executor(d.tx).QueryRowContext(ctx, q, promotionID, userID).Scan(&count)
+	return
+}
+
+// InsertUsage implements Repository.
+func (d *dbRepository) InsertUsage(ctx context.Context, usage *PromotionUsages) (err error) {
+	q := `
+		INSERT INTO promotion_usages (
+			promotion_id, user_id, order_id, discount
+		) VALUES (
+			$1, $2, $3, $4
+		);
+	`
+	_, err = d.db.Executor(d.tx).ExecContext(ctx, q, usage.PromotionID, usage.UserID, usage.OrderID, usage.Discount)
+	if err != nil {
+		return
+	}
+	q = `
+		UPDATE promotions
+		SET usage_count = usage_count + 1
+		WHERE id = $1;
+	`
+	_, err = d.db.Executor(d.tx).ExecContext(ctx, q, usage.PromotionID)
+	return
+}
+
+func uniqueCodeError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrCodeAlreadyExists
+	}
+	return err
+}
+
+func expectOneRow(res sql.Result) error {
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrPromotionNotFound
+	}
+	return nil
+}
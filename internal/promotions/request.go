@@ -0,0 +1,86 @@
+package promotions
+
+import (
+	"encoding/json"
+	"regexp"
+	"time"
+
+	merchantitems "github.com/citadel-corp/belimang/internal/merchant_items"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+var codePattern = regexp.MustCompile("^[A-Za-z0-9_-]+$")
+
+type CreatePromotionPayload struct {
+	Code              string                       `json:"code"`
+	DiscountType      DiscountType                 `json:"discountType"`
+	DiscountValue     int                          `json:"discountValue"`
+	MaxDiscount       *int                         `json:"maxDiscount"`
+	MerchantIDs       []string                     `json:"merchantIds"`
+	ItemCategories    []merchantitems.ItemCategory `json:"itemCategories"`
+	MinSpend          int                          `json:"minSpend"`
+	UsageLimit        *int                         `json:"usageLimit"`
+	UsageLimitPerUser *int                         `json:"usageLimitPerUser"`
+	StartsAt          time.Time                    `json:"startsAt"`
+	EndsAt            *time.Time                   `json:"endsAt"`
+}
+
+func (p CreatePromotionPayload) Validate() error {
+	maxDiscountValue := validation.Max(1 << 30)
+	if p.DiscountType == Percentage {
+		maxDiscountValue = validation.Max(100)
+	}
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Code, validation.Required, validation.Length(MinCode, MaxCode), validation.Match(codePattern)),
+		validation.Field(&p.DiscountType, validation.Required, validation.In(DiscountTypes...)),
+		validation.Field(&p.DiscountValue, validation.Required, validation.Min(1), maxDiscountValue),
+		validation.Field(&p.MaxDiscount, validation.NilOrNotEmpty, validation.Min(1)),
+		validation.Field(&p.MerchantIDs, validation.Each(validation.Required)),
+		validation.Field(&p.ItemCategories, validation.Each(validation.In(merchantitems.ProductCategories...))),
+		validation.Field(&p.MinSpend, validation.Min(0)),
+		validation.Field(&p.UsageLimit, validation.NilOrNotEmpty, validation.Min(1)),
+		validation.Field(&p.UsageLimitPerUser, validation.NilOrNotEmpty, validation.Min(1)),
+		validation.Field(&p.StartsAt, validation.Required),
+		validation.Field(&p.EndsAt, validation.NilOrNotEmpty, validation.Min(p.StartsAt.Add(time.Second))),
+	)
+}
+
+// UpdatePromotionPayload only changes the fields that are set. It is validated with
+// the CreatePromotionPayload rules after being merged with the stored promotion.
+// The optional caps and the end are removed by setting them to null.
+type UpdatePromotionPayload struct {
+	Code              *string                       `json:"code"`
+	DiscountType      *DiscountType                 `json:"discountType"`
+	DiscountValue     *int                          `json:"discountValue"`
+	MaxDiscount       Nullable[int]                 `json:"maxDiscount"`
+	MerchantIDs       *[]string                     `json:"merchantIds"`
+	ItemCategories    *[]merchantitems.ItemCategory `json:"itemCategories"`
+	MinSpend          *int                          `json:"minSpend"`
+	UsageLimit        Nullable[int]                 `json:"usageLimit"`
+	UsageLimitPerUser Nullable[int]                 `json:"usageLimitPerUser"`
+	StartsAt          *time.Time                    `json:"startsAt"`
+	EndsAt            Nullable[time.Time]           `json:"endsAt"`
+}
+
+// Nullable is a field that tells being left out, Set false, from being null,
+// Set with a nil Value.
+type Nullable[T any] struct {
+	Set   bool
+	Value *T
+}
+
+func (n *Nullable[T]) UnmarshalJSON(b []byte) error {
+	n.Set = true
+	if string(b) == "null" {
+		n.Value = nil
+		return nil
+	}
+	n.Value = new(T)
+	return json.Unmarshal(b, n.Value)
+}
+
+type ListPromotionsPayload struct {
+	Code   string `schema:"code" binding:"omitempty"`
+	Limit  int    `schema:"limit" binding:"omitempty"`
+	Offset int    `schema:"offset" binding:"omitempty"`
+}
@@ -0,0 +1,59 @@
+package promotions
+
+import (
+	"time"
+
+	merchantitems "github.com/citadel-corp/belimang/internal/merchant_items"
+)
+
+type PromotionUIDResponse struct {
+	UID string `json:"promotionId"`
+}
+
+type PromotionResponse struct {
+	UID               string                       `json:"promotionId"`
+	Code              string                       `json:"code"`
+	DiscountType      DiscountType                 `json:"discountType"`
+	DiscountValue     int                          `json:"discountValue"`
+	MaxDiscount       *int                         `json:"maxDiscount"`
+	MerchantIDs       []string                     `json:"merchantIds"`
+	ItemCategories    []merchantitems.ItemCategory `json:"itemCategories"`
+	MinSpend          int                          `json:"minSpend"`
+	UsageLimit        *int                         `json:"usageLimit"`
+	UsageLimitPerUser *int                         `json:"usageLimitPerUser"`
+	UsageCount        int                          `json:"usageCount"`
+	StartsAt          time.Time                    `json:"startsAt"`
+	EndsAt            *time.Time                   `json:"endsAt"`
+	CreatedAt         time.Time                    `json:"createdAt"`
+}
+
+func CreatePromotionResponse(p Promotions) PromotionResponse {
+	merchantIDs := make([]string, 0, len(p.MerchantUIDs))
+	merchantIDs = append(merchantIDs, p.MerchantUIDs...)
+	itemCategories := make([]merchantitems.ItemCategory, 0, len(p.ItemCategories))
+	itemCategories = append(itemCategories, p.ItemCategories...)
+	return PromotionResponse{
+		UID:               p.UID,
+		Code:              p.Code,
+		DiscountType:      p.DiscountType,
+		DiscountValue:     p.DiscountValue,
+		MaxDiscount:       p.MaxDiscount,
+		MerchantIDs:       merchantIDs,
+		ItemCategories:    itemCategories,
+		MinSpend:          p.MinSpend,
+		UsageLimit:        p.UsageLimit,
+		UsageLimitPerUser: p.UsageLimitPerUser,
+		UsageCount:        p.UsageCount,
+		StartsAt:          p.StartsAt,
+		EndsAt:            p.EndsAt,
+		CreatedAt:         p.CreatedAt,
+	}
+}
+
+func CreatePromotionListResponse(promotions []Promotions) []PromotionResponse {
+	promotionsResponse := make([]PromotionResponse, 0)
+	for _, p := range promotions {
+		promotionsResponse = append(promotionsResponse, CreatePromotionResponse(p))
+	}
+	return promotionsResponse
+}
@@ -0,0 +1,166 @@
+package promotions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/citadel-corp/belimang/internal/common/id"
+	"github.com/citadel-corp/belimang/internal/common/response"
+)
+
+type Service interface {
+	Create(ctx context.Context, req CreatePromotionPayload) (*PromotionUIDResponse, error)
+	List(ctx context.Context, req ListPromotionsPayload) ([]PromotionResponse, *response.Pagination, error)
+	GetByUID(ctx context.Context, uid string) (*PromotionResponse, error)
+	Update(ctx context.Context, uid string, req UpdatePromotionPayload) (*PromotionResponse, error)
+	Delete(ctx context.Context, uid string) error
+}
+
+type promotionService struct {
+	repository Repository
+}
+
+func NewService(repository Repository) Service {
+	return &promotionService{repository: repository}
+}
+
+func (s *promotionService) Create(ctx context.Context, req CreatePromotionPayload) (*PromotionUIDResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	promotion := &Promotions{
+		UID:               id.GenerateStringID(16),
+		Code:              strings.ToUpper(req.Code),
+		DiscountType:      req.DiscountType,
+		DiscountValue:     req.DiscountValue,
+		MaxDiscount:       req.MaxDiscount,
+		MerchantUIDs:      req.MerchantIDs,
+		ItemCategories:    req.ItemCategories,
+		MinSpend:          req.MinSpend,
+		UsageLimit:        req.UsageLimit,
+		UsageLimitPerUser: req.UsageLimitPerUser,
+		StartsAt:          req.StartsAt.UTC(),
+		EndsAt:            utc(req.EndsAt),
+	}
+	err = s.repository.Create(ctx, promotion)
+	if err != nil {
+		return nil, err
+	}
+	return &PromotionUIDResponse{
+		UID: promotion.UID,
+	}, nil
+}
+
+func (s *promotionService) List(ctx context.Context, req ListPromotionsPayload) ([]PromotionResponse, *response.Pagination, error) {
+	if req.Limit == 0 {
+		req.Limit = 5
+	}
+
+	promotions, pagination, err := s.repository.List(ctx, req)
+	if err != nil {
+		return []PromotionResponse{}, nil, err
+	}
+
+	return CreatePromotionListResponse(promotions), pagination, nil
+}
+
+func (s *promotionService) GetByUID(ctx context.Context, uid string) (*PromotionResponse, error) {
+	promotion, err := s.repository.GetByUID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	resp := CreatePromotionResponse(*promotion)
+	return &resp, nil
+}
+
+func (s *promotionService) Update(ctx context.Context, uid string, req UpdatePromotionPayload) (*PromotionResponse, error) {
+	promotion, err := s.repository.GetByUID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := CreatePromotionPayload{
+		Code:              promotion.Code,
+		DiscountType:      promotion.DiscountType,
+		DiscountValue:     promotion.DiscountValue,
+		MaxDiscount:       promotion.MaxDiscount,
+		MerchantIDs:       promotion.MerchantUIDs,
+		ItemCategories:    promotion.ItemCategories,
+		MinSpend:          promotion.MinSpend,
+		UsageLimit:        promotion.UsageLimit,
+		UsageLimitPerUser: promotion.UsageLimitPerUser,
+		StartsAt:          promotion.StartsAt,
+		EndsAt:            promotion.EndsAt,
+	}
+	if req.Code != nil {
+		merged.Code = *req.Code
+	}
+	if req.DiscountType != nil {
+		merged.DiscountType = *req.DiscountType
+	}
+	if req.DiscountValue != nil {
+		merged.DiscountValue = *req.DiscountValue
+	}
+	if req.MaxDiscount.Set {
+		merged.MaxDiscount = req.MaxDiscount.Value
+	}
+	if req.MerchantIDs != nil {
+		merged.MerchantIDs = *req.MerchantIDs
+	}
+	if req.ItemCategories != nil {
+		merged.ItemCategories = *req.ItemCategories
+	}
+	if req.MinSpend != nil {
+		merged.MinSpend = *req.MinSpend
+	}
+	if req.UsageLimit.Set {
+		merged.UsageLimit = req.UsageLimit.Value
+	}
+	if req.UsageLimitPerUser.Set {
+		merged.UsageLimitPerUser = req.UsageLimitPerUser.Value
+	}
+	if req.StartsAt != nil {
+		merged.StartsAt = *req.StartsAt
+	}
+	if req.EndsAt.Set {
+		merged.EndsAt = req.EndsAt.Value
+	}
+	err = merged.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+
+	promotion.Code = strings.ToUpper(merged.Code)
+	promotion.DiscountType = merged.DiscountType
+	promotion.DiscountValue = merged.DiscountValue
+	promotion.MaxDiscount = merged.MaxDiscount
+	promotion.MerchantUIDs = merged.MerchantIDs
+	promotion.ItemCategories = merged.ItemCategories
+	promotion.MinSpend = merged.MinSpend
+	promotion.UsageLimit = merged.UsageLimit
+	promotion.UsageLimitPerUser = merged.UsageLimitPerUser
+	promotion.StartsAt = merged.StartsAt.UTC()
+	promotion.EndsAt = utc(merged.EndsAt)
+	err = s.repository.Update(ctx, promotion)
+	if err != nil {
+		return nil, err
+	}
+	resp := CreatePromotionResponse(*promotion)
+	return &resp, nil
+}
+
+func (s *promotionService) Delete(ctx context.Context, uid string) error {
+	return s.repository.Delete(ctx, uid)
+}
+
+// utc stores times as UTC since promotion windows live in TIMESTAMP columns.
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
@@ -0,0 +1,107 @@
+package promotions
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// fakeRepository keeps a single promotion.
+type fakeRepository struct {
+	Repository
+	promotion Promotions
+}
+
+func (r *fakeRepository) GetByUID(ctx context.Context, uid string) (*Promotions, error) {
+	if uid != r.promotion.UID {
+		return nil, ErrPromotionNotFound
+	}
+	promotion := r.promotion
+	return &promotion, nil
+}
+
+func (r *fakeRepository) Update(ctx context.Context, promotion *Promotions) error {
+	r.promotion = *promotion
+	return nil
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestUpdateClearsNullFields(t *testing.T) {
+	startsAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	endsAt := startsAt.AddDate(0, 1, 0)
+	stored := Promotions{
+		UID:               "promotion0000001",
+		Code:              "HEMAT",
+		DiscountType:      Percentage,
+		DiscountValue:     10,
+		MaxDiscount:       intPtr(5000),
+		MerchantUIDs:      MerchantUIDs{},
+		ItemCategories:    ItemCategories{},
+		UsageLimit:        intPtr(100),
+		UsageLimitPerUser: intPtr(1),
+		StartsAt:          startsAt,
+		EndsAt:            &endsAt,
+	}
+
+	tests := []struct {
+		name                  string
+		body                  string
+		wantMaxDiscount       *int
+		wantUsageLimit        *int
+		wantUsageLimitPerUser *int
+		wantEndsAt            *time.Time
+	}{
+		{
+			name:                  "left out fields are kept",
+			body:                  `{"discountValue": 20}`,
+			wantMaxDiscount:       intPtr(5000),
+			wantUsageLimit:        intPtr(100),
+			wantUsageLimitPerUser: intPtr(1),
+			wantEndsAt:            &endsAt,
+		},
+		{
+			name: "null fields are cleared",
+			body: `{"maxDiscount": null, "usageLimit": null, "usageLimitPerUser": null, "endsAt": null}`,
+		},
+		{
+			name:                  "set fields are changed",
+			body:                  `{"maxDiscount": 2000, "usageLimit": 50, "usageLimitPerUser": 2, "endsAt": "2026-03-01T00:00:00Z"}`,
+			wantMaxDiscount:       intPtr(2000),
+			wantUsageLimit:        intPtr(50),
+			wantUsageLimitPerUser: intPtr(2),
+			wantEndsAt:            func() *time.Time { t := startsAt.AddDate(0, 2, 0); return &t }(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req UpdatePromotionPayload
+			if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+				t.Fatalf("decoding %s: %v", tt.body, err)
+			}
+			repository := &fakeRepository{promotion: stored}
+			service := NewService(repository)
+
+			if _, err := service.Update(context.Background(), stored.UID, req); err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			got := repository.promotion
+			assertIntPtr(t, "maxDiscount", got.MaxDiscount, tt.wantMaxDiscount)
+			assertIntPtr(t, "usageLimit", got.UsageLimit, tt.wantUsageLimit)
+			assertIntPtr(t, "usageLimitPerUser", got.UsageLimitPerUser, tt.wantUsageLimitPerUser)
+			if (got.EndsAt == nil) != (tt.wantEndsAt == nil) || got.EndsAt != nil && !got.EndsAt.Equal(*tt.wantEndsAt) {
+				t.Errorf("endsAt = %v, want %v", got.EndsAt, tt.wantEndsAt)
+			}
+		})
+	}
+}
+
+func assertIntPtr(t *testing.T, field string, got, want *int) {
+	t.Helper()
+	if (got == nil) != (want == nil) || got != nil && *got != *want {
+		t.Errorf("%s = %v, want %v", field, got, want)
+	}
+}
@@ -0,0 +1,7 @@
+package search
+
+import "errors"
+
+var (
+	ErrValidationFailed = errors.New("validation failed")
+)
@@ -0,0 +1,106 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/citadel-corp/belimang/internal/common/db"
+	"github.com/citadel-corp/belimang/internal/common/response"
+	"github.com/citadel-corp/belimang/internal/common/textsearch"
+)
+
+type Repository interface {
+	WithTx(tx *sql.Tx) Repository
+	SearchMerchants(ctx context.Context, search textsearch.Query, limit, offset int) (merchants []MerchantResults, pagination *response.Pagination, err error)
+	SearchItems(ctx context.Context, search textsearch.Query, limit, offset int) (items []ItemResults, pagination *response.Pagination, err error)
+}
+
+type dbRepository struct {
+	db *db.DB
+	tx *sql.Tx
+}
+
+func NewRepository(db *db.DB) Repository {
+	return &dbRepository{db: db}
+}
+
+// WithTx implements Repository.
+func (d *dbRepository) WithTx(tx *sql.Tx) Repository {
+	return &dbRepository{db: d.db, tx: tx}
+}
+
+// SearchMerchants implements Repository.
+func (d *dbRepository) SearchMerchants(ctx context.Context, search textsearch.Query, limit, offset int) (merchants []MerchantResults, pagination *response.Pagination, err error) {
+	merchants = make([]MerchantResults, 0)
+
+	q := db.NewQuery(fmt.Sprintf(`
+		SELECT COUNT(*) OVER() AS total_count, m.uid, m.name, m.merchant_category, m.image_url, m.location_lat, m.location_lng, m.created_at,
+		%s AS rank
+		FROM merchants m
+	`, textsearch.Rank("m.name", "m.search_vector")), search.RankArgs()...)
+	q.Where("m.deleted_at IS NULL")
+	q.Where(textsearch.Condition("m.name", "m.search_vector"), search.ConditionArgs()...)
+	q.OrderBy("rank DESC").OrderBy("m.created_at DESC")
+	q.Page(limit, offset)
+
+	query, args := q.Build()
+	rows, err := d.db.Executor(d.tx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	pagination = &response.Pagination{}
+	pagination.Limit = limit
+	pagination.Offset = offset
+
+	for rows.Next() {
+		m := MerchantResults{}
+		err = rows.Scan(&pagination.Total, &m.UID, &m.Name, &m.Category, &m.ImageURL, &m.Lat, &m.Lng, &m.CreatedAt, &m.Rank)
+		if err != nil {
+			return
+		}
+		merchants = append(merchants, m)
+	}
+	return
+}
+
+// SearchItems implements Repository.
+func (d *dbRepository) SearchItems(ctx context.Context, search textsearch.Query, limit, offset int) (items []ItemResults, pagination *response.Pagination, err error) {
+	items = make([]ItemResults, 0)
+
+	q := db.NewQuery(fmt.Sprintf(`
+		SELECT COUNT(*) OVER() AS total_count, mi.uid, mi.name, mi.item_category, mi.price, mi.image_url, mi.is_available, mi.created_at,
+		m.uid, m.name,
+		%s AS rank
+		FROM merchant_items mi
+		JOIN merchants m ON m.id = mi.merchant_id AND m.deleted_at IS NULL
+	`, textsearch.Rank("mi.name", "mi.search_vector")), search.RankArgs()...)
+	q.Where("mi.deleted_at IS NULL")
+	q.Where(textsearch.Condition("mi.name", "mi.search_vector"), search.ConditionArgs()...)
+	q.OrderBy("rank DESC").OrderBy("mi.created_at DESC")
+	q.Page(limit, offset)
+
+	query, args := q.Build()
+	rows, err := d.db.Executor(d.tx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	pagination = &response.Pagination{}
+	pagination.Limit = limit
+	pagination.Offset = offset
+
+	for rows.Next() {
+		i := ItemResults{}
+		err = rows.Scan(&pagination.Total, &i.UID, &i.Name, &i.Category, &i.Price, &i.ImageURL, &i.IsAvailable, &i.CreatedAt,
+			&i.MerchantUID, &i.MerchantName, &i.Rank)
+		if err != nil {
+			return
+		}
+		items = append(items, i)
+	}
+	return
+}
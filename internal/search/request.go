@@ -0,0 +1,23 @@
+package search
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+var MaxQuery = 100
+
+type SearchPayload struct {
+	Query  string     `schema:"q"`
+	Type   ResultType `schema:"type" binding:"omitempty"`
+	Limit  int        `schema:"limit" binding:"omitempty"`
+	Offset int        `schema:"offset" binding:"omitempty"`
+}
+
+func (p SearchPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Query, validation.Required, validation.Length(1, MaxQuery)),
+		validation.Field(&p.Type, validation.In(ResultTypes...)),
+		validation.Field(&p.Limit, validation.Min(0)),
+		validation.Field(&p.Offset, validation.Min(0)),
+	)
+}
@@ -0,0 +1,74 @@
+package search
+
+import (
+	"github.com/citadel-corp/belimang/internal/common/response"
+	merchantitems "github.com/citadel-corp/belimang/internal/merchant_items"
+	"github.com/citadel-corp/belimang/internal/merchants"
+)
+
+// SearchResponse holds the ranked merchants and items, each paged on its own.
+type SearchResponse struct {
+	Merchants *MerchantListResponse `json:"merchants,omitempty"`
+	Items     *ItemListResponse     `json:"items,omitempty"`
+}
+
+type MerchantListResponse struct {
+	Data []MerchantResponse   `json:"data"`
+	Meta *response.Pagination `json:"meta"`
+}
+
+type ItemListResponse struct {
+	Data []ItemResponse       `json:"data"`
+	Meta *response.Pagination `json:"meta"`
+}
+
+type MerchantResponse struct {
+	merchants.MerchantsResponse
+	Score float64 `json:"score"`
+}
+
+type ItemResponse struct {
+	merchantitems.MerchantItemResponse
+	MerchantID   string  `json:"merchantId"`
+	MerchantName string  `json:"merchantName"`
+	Score        float64 `json:"score"`
+}
+
+func CreateMerchantListResponse(results []MerchantResults, pagination *response.Pagination) *MerchantListResponse {
+	data := make([]MerchantResponse, 0, len(results))
+	for _, m := range results {
+		data = append(data, MerchantResponse{
+			MerchantsResponse: merchants.MerchantsResponse{
+				UID:       m.UID,
+				Name:      m.Name,
+				Category:  string(m.Category),
+				ImageURL:  m.ImageURL,
+				Location:  merchants.LocationResponse{Lat: m.Lat, Lng: m.Lng},
+				CreatedAt: m.CreatedAt.Nanosecond(),
+			},
+			Score: m.Rank,
+		})
+	}
+	return &MerchantListResponse{Data: data, Meta: pagination}
+}
+
+func CreateItemListResponse(results []ItemResults, pagination *response.Pagination) *ItemListResponse {
+	data := make([]ItemResponse, 0, len(results))
+	for _, i := range results {
+		data = append(data, ItemResponse{
+			MerchantItemResponse: merchantitems.MerchantItemResponse{
+				UID:             i.UID,
+				Name:            i.Name,
+				ProductCategory: i.Category,
+				Price:           i.Price,
+				ImageURL:        i.ImageURL,
+				IsAvailable:     i.IsAvailable,
+				CreatedAt:       i.CreatedAt.Nanosecond(),
+			},
+			MerchantID:   i.MerchantUID,
+			MerchantName: i.MerchantName,
+			Score:        i.Rank,
+		})
+	}
+	return &ItemListResponse{Data: data, Meta: pagination}
+}
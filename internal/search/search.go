@@ -0,0 +1,44 @@
+package search
+
+import (
+	"time"
+
+	merchantitems "github.com/citadel-corp/belimang/internal/merchant_items"
+	"github.com/citadel-corp/belimang/internal/merchants"
+)
+
+type ResultType string
+
+var (
+	MerchantResult ResultType = "merchant"
+	ItemResult     ResultType = "item"
+)
+
+var ResultTypes = []interface{}{
+	MerchantResult,
+	ItemResult,
+}
+
+type MerchantResults struct {
+	UID       string
+	Name      string
+	Category  merchants.MerchantCategory
+	ImageURL  string
+	Lat       float64
+	Lng       float64
+	Rank      float64
+	CreatedAt time.Time
+}
+
+type ItemResults struct {
+	UID          string
+	Name         string
+	Category     merchantitems.ItemCategory
+	Price        int
+	ImageURL     string
+	IsAvailable  bool
+	MerchantUID  string
+	MerchantName string
+	Rank         float64
+	CreatedAt    time.Time
+}
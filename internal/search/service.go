@@ -0,0 +1,52 @@
+package search
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/citadel-corp/belimang/internal/common/textsearch"
+)
+
+type Service interface {
+	Search(ctx context.Context, req SearchPayload) (*SearchResponse, error)
+}
+
+type searchService struct {
+	repository Repository
+}
+
+func NewService(repository Repository) Service {
+	return &searchService{repository: repository}
+}
+
+// Search implements Service. Merchants and items are searched unless the request asks for one type.
+func (s *searchService) Search(ctx context.Context, req SearchPayload) (*SearchResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	query := textsearch.Parse(req.Query)
+	if query.IsEmpty() {
+		return nil, fmt.Errorf("%w: q: must contain a letter or digit", ErrValidationFailed)
+	}
+	if req.Limit == 0 {
+		req.Limit = 5
+	}
+
+	resp := &SearchResponse{}
+	if req.Type == "" || req.Type == MerchantResult {
+		merchants, pagination, err := s.repository.SearchMerchants(ctx, query, req.Limit, req.Offset)
+		if err != nil {
+			return nil, err
+		}
+		resp.Merchants = CreateMerchantListResponse(merchants, pagination)
+	}
+	if req.Type == "" || req.Type == ItemResult {
+		items, pagination, err := s.repository.SearchItems(ctx, query, req.Limit, req.Offset)
+		if err != nil {
+			return nil, err
+		}
+		resp.Items = CreateItemListResponse(items, pagination)
+	}
+	return resp, nil
+}
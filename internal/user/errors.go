@@ -1,10 +1,37 @@
 package user
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 var (
 	ErrUserNotFound      = errors.New("user not found")
-	ErrWrongPassword     = errors.New("wrong password")
+	ErrWrongPassword     = errors.New("invalid username or password")
 	ErrUserAlreadyExists = errors.New("user already exists")
 	ErrValidationFailed  = errors.New("validation failed")
+
+	ErrInvalidPermission = errors.New("invalid permission")
+
+	ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+
+	ErrLoginThrottled = errors.New("too many failed logins")
+	ErrLoginLocked    = errors.New("login locked after too many failed logins")
 )
+
+// LoginThrottledError is returned by Login while the username or the IP address
+// is backing off or locked out. It wraps ErrLoginThrottled or ErrLoginLocked.
+type LoginThrottledError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("%s, retry in %s", e.Err, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LoginThrottledError) Unwrap() error {
+	return e.Err
+}
@@ -3,9 +3,13 @@ package user
 import (
 	"errors"
 	"net/http"
+	"strconv"
 
+	"github.com/citadel-corp/belimang/internal/common/jwt"
+	"github.com/citadel-corp/belimang/internal/common/middleware"
 	"github.com/citadel-corp/belimang/internal/common/request"
 	"github.com/citadel-corp/belimang/internal/common/response"
+	"github.com/gorilla/mux"
 )
 
 type Handler struct {
@@ -112,7 +116,164 @@ func (h *Handler) LoginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userResp, err := h.service.Login(r.Context(), req)
+	userResp, err := h.service.Login(r.Context(), req, request.ClientIP(r))
+	var throttled *LoginThrottledError
+	if errors.As(err, &throttled) {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(throttled.RetryAfter)))
+		message := "Too many failed logins"
+		if errors.Is(err, ErrLoginLocked) {
+			message = "Login locked"
+		}
+		response.JSON(w, http.StatusTooManyRequests, response.ResponseBody{
+			Message: message,
+			Error:   err.Error(),
+		})
+		return
+	}
+	// unknown usernames and wrong passwords look the same, not to tell which users exist
+	if errors.Is(err, ErrWrongPassword) {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Invalid username or password",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, userResp)
+}
+
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshPayload
+
+	err := request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	err = req.Validate()
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	userResp, err := h.service.Refresh(r.Context(), req)
+	if errors.Is(err, ErrRefreshTokenInvalid) {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Invalid refresh token",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrRefreshTokenReused) {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Refresh token reused, session revoked",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, userResp)
+}
+
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.ContextAuthKey{}).(*jwt.UserClaims)
+	if !ok {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Unauthorized",
+			Error:   "cannot parse auth value from context",
+		})
+		return
+	}
+
+	err := h.service.Logout(r.Context(), claims)
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "Logged out successfully",
+	})
+}
+
+func (h *Handler) CreateStaff(w http.ResponseWriter, r *http.Request) {
+	var req CreateStaffPayload
+
+	err := request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	staffResp, err := h.service.CreateStaff(r.Context(), req)
+	if errors.Is(err, ErrValidationFailed) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, ErrUserAlreadyExists) {
+		response.JSON(w, http.StatusConflict, response.ResponseBody{
+			Message: "User already exists",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusCreated, staffResp)
+}
+
+func (h *Handler) UpdatePermissions(w http.ResponseWriter, r *http.Request) {
+	var req UpdatePermissionsPayload
+
+	err := request.DecodeJSON(w, r, &req)
+	if err != nil {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Failed to decode JSON",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	staffResp, err := h.service.UpdatePermissions(r.Context(), mux.Vars(r)["userId"], req)
+	if errors.Is(err, ErrValidationFailed) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
 	if errors.Is(err, ErrUserNotFound) {
 		response.JSON(w, http.StatusNotFound, response.ResponseBody{
 			Message: "User not found",
@@ -120,7 +281,40 @@ func (h *Handler) LoginUser(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
-	if errors.Is(err, ErrWrongPassword) {
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, staffResp)
+}
+
+func (h *Handler) ListBlockedLogins(w http.ResponseWriter, r *http.Request) {
+	res, err := h.service.ListBlockedLogins(r.Context())
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetClaims(r.Context())
+	if !ok {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Unauthorized",
+			Error:   "cannot parse auth value from context",
+		})
+		return
+	}
+
+	err := h.service.UnlockUser(r.Context(), mux.Vars(r)["userId"], claims.UserUID)
+	if errors.Is(err, ErrUserNotFound) {
 		response.JSON(w, http.StatusNotFound, response.ResponseBody{
 			Message: "User not found",
 			Error:   err.Error(),
@@ -134,5 +328,43 @@ func (h *Handler) LoginUser(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
-	response.JSON(w, http.StatusOK, userResp)
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "Unlocked successfully",
+	})
+}
+
+func (h *Handler) UnblockIP(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetClaims(r.Context())
+	if !ok {
+		response.JSON(w, http.StatusUnauthorized, response.ResponseBody{
+			Message: "Unauthorized",
+			Error:   "cannot parse auth value from context",
+		})
+		return
+	}
+
+	err := h.service.UnblockIP(r.Context(), mux.Vars(r)["ip"], claims.UserUID)
+	if errors.Is(err, ErrValidationFailed) {
+		response.JSON(w, http.StatusBadRequest, response.ResponseBody{
+			Message: "Bad request",
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		response.JSON(w, http.StatusInternalServerError, response.ResponseBody{
+			Message: "Internal server error",
+			Error:   err.Error(),
+		})
+		return
+	}
+	response.JSON(w, http.StatusOK, response.ResponseBody{
+		Message: "Unblocked successfully",
+	})
+}
+
+// JWKS publishes the public keys verifying the access tokens, for other services.
+func (h *Handler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	response.JSON(w, http.StatusOK, jwt.PublicKeys())
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/citadel-corp/belimang/internal/common/db"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -11,20 +12,40 @@ import (
 )
 
 type Repository interface {
+	WithTx(tx *sql.Tx) Repository
 	Create(ctx context.Context, user *Users) (err error)
 	GetByUsername(ctx context.Context, username string) (user *Users, err error)
-	// GetByUID(ctx context.Context, uid string) (user *Users, err error)
+	GetByUID(ctx context.Context, uid string) (user *Users, err error)
+	ListPermissions(ctx context.Context, userID uint64) (permissions []string, err error)
+	ReplacePermissions(ctx context.Context, userID uint64, permissions []string) (err error)
+	CreateRefreshToken(ctx context.Context, token *RefreshTokens, ttl time.Duration) (err error)
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (token *RefreshTokens, err error)
+	MarkRefreshTokenUsed(ctx context.Context, id uint64) (err error)
+	RevokeSession(ctx context.Context, familyID string, ttl time.Duration) (err error)
+	IsSessionRevoked(ctx context.Context, familyID string) (revoked bool, err error)
+	GetLoginThrottle(ctx context.Context, kind LoginThrottleKind, value string) (throttle *LoginThrottles, err error)
+	RecordLoginFailure(ctx context.Context, kind LoginThrottleKind, value string, window time.Duration) (throttle *LoginThrottles, err error)
+	BlockLogin(ctx context.Context, kind LoginThrottleKind, value string, d time.Duration, locked bool) (err error)
+	ClearLoginThrottle(ctx context.Context, kind LoginThrottleKind, value string) (cleared bool, err error)
+	ListBlockedLogins(ctx context.Context) (throttles []*LoginThrottles, err error)
+	CreateLoginAudit(ctx context.Context, audit *LoginAudits) (err error)
 	// GetByID(ctx context.Context, id uint64) (user *Users, err error)
 }
 
 type dbRepository struct {
 	db *db.DB
+	tx *sql.Tx
 }
 
 func NewRepository(db *db.DB) Repository {
 	return &dbRepository{db: db}
 }
 
+// WithTx implements Repository.
+func (d *dbRepository) WithTx(tx *sql.Tx) Repository {
+	return &dbRepository{db: d.db, tx: tx}
+}
+
 // Create implements Repository.
 func (d *dbRepository) Create(ctx context.Context, user *Users) (err error) {
 	createUserQuery := `
@@ -35,7 +56,7 @@ func (d *dbRepository) Create(ctx context.Context, user *Users) (err error) {
 		)
 		RETURNING id;
 	`
-	_, err = d.db.DB().ExecContext(ctx, createUserQuery, user.UID, user.Username, user.Email, user.HashedPassword, user.UserType)
+	err = d.db.Executor(d.tx).QueryRowContext(ctx, createUserQuery, user.UID, user.Username, user.Email, user.HashedPassword, user.UserType).Scan(&user.ID)
 	var pgErr *pgconn.PgError
 	if err != nil {
 		log.Debug().Msgf("error creating user: %v", err)
@@ -59,7 +80,7 @@ func (d *dbRepository) GetByUsername(ctx context.Context, username string) (user
 		FROM users
 		WHERE username = $1;
 	`
-	row := d.db.DB().QueryRowContext(ctx, getUserQuery, username)
+	row := d.db.Executor(d.tx).QueryRowContext(ctx, getUserQuery, username)
 	user = &Users{}
 	err = row.Scan(&user.ID, &user.UID, &user.Username, &user.Email, &user.HashedPassword, &user.UserType, &user.CreatedAt)
 	if errors.Is(err, sql.ErrNoRows) {
@@ -72,7 +93,286 @@ func (d *dbRepository) GetByUsername(ctx context.Context, username string) (user
 	return
 }
 
+// CreateRefreshToken implements Repository.
+// The expiry is computed by the database, like every other timestamp.
+func (d *dbRepository) CreateRefreshToken(ctx context.Context, token *RefreshTokens, ttl time.Duration) (err error) {
+	q := `
+		INSERT INTO refresh_tokens (
+			token_hash, family_id, user_id, expires_at
+		) VALUES (
+			$1, $2, $3, current_timestamp + make_interval(secs => $4)
+		)
+		RETURNING id, expires_at;
+	`
+	err = d.db.Executor(d.tx).QueryRowContext(ctx, q, token.TokenHash, token.FamilyID, token.UserID, ttl.Seconds()).Scan(&token.ID, &token.ExpiresAt)
+	return
+}
+
+// GetRefreshTokenByHash implements Repository.
+// Expired tokens are not found.
+// The token row stays locked until the transaction ends, so that concurrent
+// refreshes with the same token are told apart as a reuse.
+func (d *dbRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (token *RefreshTokens, err error) {
+	q := `
+		SELECT rt.id, rt.token_hash, rt.family_id, rt.user_id, u.uid, u.user_type, rt.expires_at, rt.used_at, rt.revoked_at, rt.created_at
+		FROM refresh_tokens rt
+		JOIN users u ON u.id = rt.user_id
+		WHERE rt.token_hash = $1 AND rt.expires_at > current_timestamp
+		FOR UPDATE OF rt;
+	`
+	row := d.db.Executor(d.tx).QueryRowContext(ctx, q, tokenHash)
+	token = &RefreshTokens{}
+	err = row.Scan(&token.ID, &token.TokenHash, &token.FamilyID, &token.UserID, &token.UserUID, &token.UserType, &token.ExpiresAt, &token.UsedAt, &token.RevokedAt, &token.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrRefreshTokenInvalid
+	}
+	return
+}
+
+// MarkRefreshTokenUsed implements Repository.
+func (d *dbRepository) MarkRefreshTokenUsed(ctx context.Context, id uint64) (err error) {
+	q := `
+		UPDATE refresh_tokens
+		SET used_at = current_timestamp
+		WHERE id = $1 AND used_at IS NULL;
+	`
+	res, err := d.db.Executor(d.tx).ExecContext(ctx, q, id)
+	if err != nil {
+		return
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if rowsAffected == 0 {
+		return ErrRefreshTokenReused
+	}
+	return
+}
+
+// RevokeSession implements Repository.
+// The refresh tokens of the family are revoked, and its access tokens listed as
+// revoked for ttl, by which time they have all expired.
+func (d *dbRepository) RevokeSession(ctx context.Context, familyID string, ttl time.Duration) (err error) {
+	q := `
+		UPDATE refresh_tokens
+		SET revoked_at = current_timestamp
+		WHERE family_id = $1 AND revoked_at IS NULL;
+	`
+	_, err = d.db.Executor(d.tx).ExecContext(ctx, q, familyID)
+	if err != nil {
+		return
+	}
+	q = `
+		INSERT INTO revoked_sessions (session_id, expires_at)
+		VALUES ($1, current_timestamp + make_interval(secs => $2))
+		ON CONFLICT (session_id) DO UPDATE SET expires_at = GREATEST(revoked_sessions.expires_at, EXCLUDED.expires_at);
+	`
+	_, err = d.db.Executor(d.tx).ExecContext(ctx, q, familyID, ttl.Seconds())
+	if err != nil {
+		return
+	}
+	// entries past their expiry no longer reject anything
+	_, err = d.db.Executor(d.tx).ExecContext(ctx, "DELETE FROM revoked_sessions WHERE expires_at <= current_timestamp;")
+	return
+}
+
+// IsSessionRevoked implements Repository.
+func (d *dbRepository) IsSessionRevoked(ctx context.Context, familyID string) (revoked bool, err error) {
+	q := `
+		SELECT EXISTS (
+			SELECT 1 FROM revoked_sessions
+			WHERE session_id = $1 AND expires_at > current_timestamp
+		);
+	`
+	err = d.db.Executor(d.tx).QueryRowContext(ctx, q, familyID).Scan(&revoked)
+	return
+}
+
+// GetByUID implements Repository.
 func (d *dbRepository) GetByUID(ctx context.Context, uid string) (user *Users, err error) {
+	getUserQuery := `
+		SELECT id, uid, username, email, hashed_password, user_type, created_at
+		FROM users
+		WHERE uid = $1;
+	`
+	row := d.db.Executor(d.tx).QueryRowContext(ctx, getUserQuery, uid)
+	user = &Users{}
+	err = row.Scan(&user.ID, &user.UID, &user.Username, &user.Email, &user.HashedPassword, &user.UserType, &user.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrUserNotFound
+	}
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// ListPermissions implements Repository.
+func (d *dbRepository) ListPermissions(ctx context.Context, userID uint64) (permissions []string, err error) {
+	q := `
+		SELECT permission
+		FROM user_permissions
+		WHERE user_id = $1
+		ORDER BY permission;
+	`
+	rows, err := d.db.Executor(d.tx).QueryContext(ctx, q, userID)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	permissions = make([]string, 0)
+	for rows.Next() {
+		var p string
+		if err = rows.Scan(&p); err != nil {
+			return
+		}
+		permissions = append(permissions, p)
+	}
+	err = rows.Err()
+	return
+}
+
+// ReplacePermissions implements Repository.
+func (d *dbRepository) ReplacePermissions(ctx context.Context, userID uint64, permissions []string) (err error) {
+	_, err = d.db.Executor(d.tx).ExecContext(ctx, "DELETE FROM user_permissions WHERE user_id = $1;", userID)
+	if err != nil {
+		return
+	}
+	q := `
+		INSERT INTO user_permissions (user_id, permission)
+		SELECT $1, p FROM unnest($2::text[]) AS p
+		ON CONFLICT DO NOTHING;
+	`
+	_, err = d.db.Executor(d.tx).ExecContext(ctx, q, userID, permissions)
+	return
+}
+
+// loginThrottleColumns selects a login_throttles row as scanned by
+// scanLoginThrottle, the time left before logins are let through being computed
+// by the database.
+const loginThrottleColumns = `
+	kind, value, failures, last_failed_at,
+	locked AND blocked_until > current_timestamp,
+	GREATEST(EXTRACT(EPOCH FROM blocked_until - current_timestamp), 0)::float8
+`
+
+func scanLoginThrottle(row interface{ Scan(...any) error }) (throttle *LoginThrottles, err error) {
+	var (
+		locked     sql.NullBool
+		retryAfter sql.NullFloat64
+	)
+	throttle = &LoginThrottles{}
+	err = row.Scan(&throttle.Kind, &throttle.Value, &throttle.Failures, &throttle.LastFailedAt, &locked, &retryAfter)
+	if err != nil {
+		return
+	}
+	throttle.Locked = locked.Bool
+	throttle.RetryAfter = time.Duration(retryAfter.Float64 * float64(time.Second))
+	return
+}
+
+// GetLoginThrottle implements Repository.
+// A username or an IP address without recent failures gets a throttle without
+// any.
+func (d *dbRepository) GetLoginThrottle(ctx context.Context, kind LoginThrottleKind, value string) (throttle *LoginThrottles, err error) {
+	q := `SELECT ` + loginThrottleColumns + ` FROM login_throttles WHERE kind = $1 AND value = $2;`
+	throttle, err = scanLoginThrottle(d.db.Executor(d.tx).QueryRowContext(ctx, q, kind, value))
+	if errors.Is(err, sql.ErrNoRows) {
+		return &LoginThrottles{Kind: kind, Value: value}, nil
+	}
+	return
+}
+
+// RecordLoginFailure implements Repository.
+// Failures start over once window passed since the last one.
+func (d *dbRepository) RecordLoginFailure(ctx context.Context, kind LoginThrottleKind, value string, window time.Duration) (throttle *LoginThrottles, err error) {
+	q := `
+		INSERT INTO login_throttles (kind, value, failures, last_failed_at)
+		VALUES ($1, $2, 1, current_timestamp)
+		ON CONFLICT (kind, value) DO UPDATE SET
+			failures = CASE
+				WHEN login_throttles.last_failed_at <= current_timestamp - make_interval(secs => $3) THEN 1
+				ELSE login_throttles.failures + 1
+			END,
+			last_failed_at = current_timestamp
+		RETURNING ` + loginThrottleColumns + `;`
+	throttle, err = scanLoginThrottle(d.db.Executor(d.tx).QueryRowContext(ctx, q, kind, value, window.Seconds()))
+	if err != nil {
+		return
+	}
+	// rows past their window and block no longer throttle anything
+	q = `
+		DELETE FROM login_throttles
+		WHERE last_failed_at <= current_timestamp - make_interval(secs => $1)
+		AND (blocked_until IS NULL OR blocked_until <= current_timestamp);
+	`
+	_, err = d.db.Executor(d.tx).ExecContext(ctx, q, window.Seconds())
+	return
+}
+
+// BlockLogin implements Repository.
+func (d *dbRepository) BlockLogin(ctx context.Context, kind LoginThrottleKind, value string, duration time.Duration, locked bool) (err error) {
+	q := `
+		UPDATE login_throttles
+		SET blocked_until = current_timestamp + make_interval(secs => $3), locked = $4
+		WHERE kind = $1 AND value = $2;
+	`
+	_, err = d.db.Executor(d.tx).ExecContext(ctx, q, kind, value, duration.Seconds(), locked)
+	return
+}
+
+// ClearLoginThrottle implements Repository.
+func (d *dbRepository) ClearLoginThrottle(ctx context.Context, kind LoginThrottleKind, value string) (cleared bool, err error) {
+	res, err := d.db.Executor(d.tx).ExecContext(ctx, "DELETE FROM login_throttles WHERE kind = $1 AND value = $2;", kind, value)
+	if err != nil {
+		return
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	cleared = rowsAffected > 0
+	return
+}
+
+// ListBlockedLogins implements Repository.
+// The longest blocks come first.
+func (d *dbRepository) ListBlockedLogins(ctx context.Context) (throttles []*LoginThrottles, err error) {
+	q := `
+		SELECT ` + loginThrottleColumns + `
+		FROM login_throttles
+		WHERE blocked_until > current_timestamp
+		ORDER BY blocked_until DESC, kind, value;
+	`
+	rows, err := d.db.Executor(d.tx).QueryContext(ctx, q)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	throttles = make([]*LoginThrottles, 0)
+	for rows.Next() {
+		var throttle *LoginThrottles
+		if throttle, err = scanLoginThrottle(rows); err != nil {
+			return
+		}
+		throttles = append(throttles, throttle)
+	}
+	err = rows.Err()
+	return
+}
+
+// CreateLoginAudit implements Repository.
+func (d *dbRepository) CreateLoginAudit(ctx context.Context, audit *LoginAudits) (err error) {
+	q := `
+		INSERT INTO login_audits (event, username, ip, actor_uid)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at;
+	`
+	err = d.db.Executor(d.tx).QueryRowContext(ctx, q, audit.Event, audit.Username, audit.IP, audit.ActorUID).Scan(&audit.ID, &audit.CreatedAt)
 	return
 }
 
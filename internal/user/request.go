@@ -1,6 +1,7 @@
 package user
 
 import (
+	"github.com/citadel-corp/belimang/internal/common/permission"
 	validations "github.com/citadel-corp/belimang/internal/common/validation"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 )
@@ -32,3 +33,43 @@ func (p LoginPayload) Validate() error {
 		validation.Field(&p.Password, validation.Required, validation.Length(MinPassword, MaxPassword)),
 	)
 }
+
+type RefreshPayload struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+func (p RefreshPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.RefreshToken, validation.Required, validation.Length(RefreshTokenLength, RefreshTokenLength)),
+	)
+}
+
+type CreateStaffPayload struct {
+	Username    string   `json:"username"`
+	Email       string   `json:"email"`
+	Password    string   `json:"password"`
+	Role        UserType `json:"role"`
+	Permissions []string `json:"permissions"`
+}
+
+func (p CreateStaffPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Username, validation.Required, validation.Length(MinUsername, MaxUsername)),
+		validation.Field(&p.Email, validation.Required, validations.EmailValidationRule),
+		validation.Field(&p.Password, validation.Required, validation.Length(5, 15)),
+		validation.Field(&p.Role, validation.Required, validation.In(StaffUserTypes...)),
+		validation.Field(&p.Permissions, validation.Each(permissionRule)),
+	)
+}
+
+type UpdatePermissionsPayload struct {
+	Permissions []string `json:"permissions"`
+}
+
+func (p UpdatePermissionsPayload) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Permissions, validation.NotNil, validation.Each(permissionRule)),
+	)
+}
+
+var permissionRule = validation.NewStringRule(permission.IsValid, ErrInvalidPermission.Error())
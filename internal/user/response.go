@@ -1,5 +1,55 @@
 package user
 
+import "time"
+
 type UserAuthResponse struct {
-	AccessToken string `json:"token"`
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+type StaffResponse struct {
+	UserID      string   `json:"userId"`
+	Username    string   `json:"username"`
+	Email       string   `json:"email"`
+	Role        UserType `json:"role"`
+	Permissions []string `json:"permissions"` // granted on top of the role's
+}
+
+func CreateStaffResponse(user *Users, permissions []string) *StaffResponse {
+	return &StaffResponse{
+		UserID:      user.UID,
+		Username:    user.Username,
+		Email:       user.Email,
+		Role:        user.UserType,
+		Permissions: permissions,
+	}
+}
+
+type BlockedLoginResponse struct {
+	Kind              LoginThrottleKind `json:"kind"`
+	Value             string            `json:"value"`
+	Failures          int               `json:"failures"`
+	Locked            bool              `json:"locked"`
+	LastFailedAt      time.Time         `json:"lastFailedAt"`
+	RetryAfterSeconds int               `json:"retryAfterSeconds"`
+}
+
+func CreateBlockedLoginsResponse(throttles []*LoginThrottles) []*BlockedLoginResponse {
+	res := make([]*BlockedLoginResponse, 0, len(throttles))
+	for _, t := range throttles {
+		res = append(res, &BlockedLoginResponse{
+			Kind:              t.Kind,
+			Value:             t.Value,
+			Failures:          t.Failures,
+			Locked:            t.Locked,
+			LastFailedAt:      t.LastFailedAt,
+			RetryAfterSeconds: retryAfterSeconds(t.RetryAfter),
+		})
+	}
+	return res
+}
+
+// retryAfterSeconds rounds d up to whole seconds, as Retry-After wants them.
+func retryAfterSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
 }
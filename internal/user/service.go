@@ -2,8 +2,15 @@ package user
 
 import (
 	"context"
-	"time"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
 
+	"github.com/citadel-corp/belimang/internal/common/db"
 	"github.com/citadel-corp/belimang/internal/common/id"
 	"github.com/citadel-corp/belimang/internal/common/jwt"
 	"github.com/citadel-corp/belimang/internal/common/password"
@@ -11,15 +18,24 @@ import (
 
 type Service interface {
 	Create(ctx context.Context, req CreateUserPayload) (*UserAuthResponse, error)
-	Login(ctx context.Context, req LoginPayload) (*UserAuthResponse, error)
+	Login(ctx context.Context, req LoginPayload, clientIP string) (*UserAuthResponse, error)
+	Refresh(ctx context.Context, req RefreshPayload) (*UserAuthResponse, error)
+	Logout(ctx context.Context, claims *jwt.UserClaims) error
+	IsRevoked(ctx context.Context, claims *jwt.UserClaims) (bool, error)
+	CreateStaff(ctx context.Context, req CreateStaffPayload) (*StaffResponse, error)
+	UpdatePermissions(ctx context.Context, uid string, req UpdatePermissionsPayload) (*StaffResponse, error)
+	ListBlockedLogins(ctx context.Context) ([]*BlockedLoginResponse, error)
+	UnlockUser(ctx context.Context, uid string, actorUID string) error
+	UnblockIP(ctx context.Context, ip string, actorUID string) error
 }
 
 type userService struct {
+	transactor db.Transactor
 	repository Repository
 }
 
-func NewService(repository Repository) Service {
-	return &userService{repository: repository}
+func NewService(transactor db.Transactor, repository Repository) Service {
+	return &userService{transactor: transactor, repository: repository}
 }
 
 func (s *userService) Create(ctx context.Context, req CreateUserPayload) (*UserAuthResponse, error) {
@@ -38,18 +54,23 @@ func (s *userService) Create(ctx context.Context, req CreateUserPayload) (*UserA
 	if err != nil {
 		return nil, err
 	}
-	// create access token with signed jwt
-	accessToken, err := jwt.Sign(time.Hour*2, user.UID, string(user.UserType))
+	return s.issueTokens(ctx, s.repository, user.ID, user.UID, user.UserType, id.GenerateStringID(16))
+}
+
+// Login checks the password of the user, unless the username or the IP address
+// failed too many logins recently. Unknown usernames and wrong passwords fail
+// the same way, in about the same time.
+func (s *userService) Login(ctx context.Context, req LoginPayload, clientIP string) (*UserAuthResponse, error) {
+	err := s.checkLoginThrottles(ctx, req.Username, clientIP)
 	if err != nil {
 		return nil, err
 	}
-	return &UserAuthResponse{
-		AccessToken: accessToken,
-	}, nil
-}
 
-func (s *userService) Login(ctx context.Context, req LoginPayload) (*UserAuthResponse, error) {
 	user, err := s.repository.GetByUsername(ctx, req.Username)
+	if errors.Is(err, ErrUserNotFound) {
+		password.Mismatch(req.Password)
+		return nil, s.loginFailed(ctx, req.Username, clientIP)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -59,16 +80,291 @@ func (s *userService) Login(ctx context.Context, req LoginPayload) (*UserAuthRes
 		return nil, err
 	}
 	if !match {
-		return nil, ErrWrongPassword
+		return nil, s.loginFailed(ctx, req.Username, clientIP)
 	}
 
-	// create access token with signed jwt
-	accessToken, err := jwt.Sign(time.Hour*2, user.UID, string(user.UserType))
+	// the address keeps its failures, logging into one account vouches for no other
+	_, err = s.repository.ClearLoginThrottle(ctx, UsernameThrottle, req.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	// every login starts a new session, its own refresh token family
+	return s.issueTokens(ctx, s.repository, user.ID, user.UID, user.UserType, id.GenerateStringID(16))
+}
+
+// Refresh rotates the refresh token: it can only be used once, and using it again
+// means it leaked, so the whole session is revoked.
+func (s *userService) Refresh(ctx context.Context, req RefreshPayload) (*UserAuthResponse, error) {
+	var (
+		resp   *UserAuthResponse
+		reused bool
+	)
+	err := s.transactor.StartTx(ctx, func(tx *sql.Tx) error {
+		repository := s.repository.WithTx(tx)
+		token, err := repository.GetRefreshTokenByHash(ctx, hashRefreshToken(req.RefreshToken))
+		if err != nil {
+			return err
+		}
+		if token.RevokedAt.Valid {
+			return ErrRefreshTokenInvalid
+		}
+		err = repository.MarkRefreshTokenUsed(ctx, token.ID)
+		if errors.Is(err, ErrRefreshTokenReused) {
+			// the revocation has to commit, so the error is only returned afterwards
+			reused = true
+			return repository.RevokeSession(ctx, token.FamilyID, AccessTokenTTL)
+		}
+		if err != nil {
+			return err
+		}
+		resp, err = s.issueTokens(ctx, repository, token.UserID, token.UserUID, token.UserType, token.FamilyID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, ErrRefreshTokenReused
+	}
+	return resp, nil
+}
+
+// Logout revokes the session of the token, its refresh tokens and every access
+// token issued in it.
+func (s *userService) Logout(ctx context.Context, claims *jwt.UserClaims) error {
+	if claims.SessionID == "" {
+		// issued before sessions existed, it expires soon enough on its own
+		return nil
+	}
+	return s.repository.RevokeSession(ctx, claims.SessionID, AccessTokenTTL)
+}
+
+// IsRevoked tells the authorization middlewares whether the session of the token
+// was revoked.
+func (s *userService) IsRevoked(ctx context.Context, claims *jwt.UserClaims) (bool, error) {
+	if claims.SessionID == "" {
+		return false, nil
+	}
+	return s.repository.IsSessionRevoked(ctx, claims.SessionID)
+}
+
+// CreateStaff creates the account of a merchant owner, courier or support agent
+// with permissions on top of the ones of their role.
+func (s *userService) CreateStaff(ctx context.Context, req CreateStaffPayload) (*StaffResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	hashedPassword, err := password.Hash(req.Password)
+	if err != nil {
+		return nil, err
+	}
+	user := &Users{
+		UID:            id.GenerateStringID(16),
+		Username:       req.Username,
+		Email:          req.Email,
+		HashedPassword: hashedPassword,
+		UserType:       req.Role,
+	}
+	permissions := uniquePermissions(req.Permissions)
+	err = s.transactor.StartTx(ctx, func(tx *sql.Tx) error {
+		repository := s.repository.WithTx(tx)
+		err := repository.Create(ctx, user)
+		if err != nil {
+			return err
+		}
+		return repository.ReplacePermissions(ctx, user.ID, permissions)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return CreateStaffResponse(user, permissions), nil
+}
+
+// UpdatePermissions replaces the permissions granted to the user. Tokens carry
+// the permissions, so the change applies from the next login or refresh.
+func (s *userService) UpdatePermissions(ctx context.Context, uid string, req UpdatePermissionsPayload) (*StaffResponse, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+	user, err := s.repository.GetByUID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	permissions := uniquePermissions(req.Permissions)
+	err = s.repository.ReplacePermissions(ctx, user.ID, permissions)
+	if err != nil {
+		return nil, err
+	}
+	return CreateStaffResponse(user, permissions), nil
+}
+
+// ListBlockedLogins implements Service.
+func (s *userService) ListBlockedLogins(ctx context.Context) ([]*BlockedLoginResponse, error) {
+	throttles, err := s.repository.ListBlockedLogins(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return CreateBlockedLoginsResponse(throttles), nil
+}
+
+// UnlockUser lifts the backoff or lockout of the username of the user, on behalf
+// of the admin actorUID.
+func (s *userService) UnlockUser(ctx context.Context, uid string, actorUID string) error {
+	user, err := s.repository.GetByUID(ctx, uid)
+	if err != nil {
+		return err
+	}
+	return s.unblockLogin(ctx, UsernameThrottle, user.Username, actorUID)
+}
+
+// UnblockIP lifts the backoff of the IP address, on behalf of the admin actorUID.
+func (s *userService) UnblockIP(ctx context.Context, ip string, actorUID string) error {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return fmt.Errorf("%w: ip: must be a valid IP address", ErrValidationFailed)
+	}
+	return s.unblockLogin(ctx, IPThrottle, parsed.String(), actorUID)
+}
+
+// unblockLogin forgets the failed logins of the username or IP address, and
+// audits it when there were any.
+func (s *userService) unblockLogin(ctx context.Context, kind LoginThrottleKind, value string, actorUID string) error {
+	return s.transactor.StartTx(ctx, func(tx *sql.Tx) error {
+		repository := s.repository.WithTx(tx)
+		cleared, err := repository.ClearLoginThrottle(ctx, kind, value)
+		if err != nil || !cleared {
+			return err
+		}
+		audit := &LoginAudits{
+			Event:    LoginUnlocked,
+			ActorUID: sql.NullString{String: actorUID, Valid: actorUID != ""},
+		}
+		if kind == UsernameThrottle {
+			audit.Username = sql.NullString{String: value, Valid: true}
+		} else {
+			audit.IP = sql.NullString{String: value, Valid: true}
+		}
+		return repository.CreateLoginAudit(ctx, audit)
+	})
+}
+
+type loginKey struct {
+	kind  LoginThrottleKind
+	value string
+}
+
+// loginKeys returns the username and the IP address a login is throttled by.
+func loginKeys(username, clientIP string) []loginKey {
+	keys := []loginKey{{kind: UsernameThrottle, value: username}}
+	if clientIP != "" {
+		keys = append(keys, loginKey{kind: IPThrottle, value: clientIP})
+	}
+	return keys
+}
+
+// checkLoginThrottles returns a LoginThrottledError while the username or the IP
+// address is blocked, with the longest of their blocks.
+func (s *userService) checkLoginThrottles(ctx context.Context, username, clientIP string) error {
+	var blocked *LoginThrottles
+	for _, key := range loginKeys(username, clientIP) {
+		throttle, err := s.repository.GetLoginThrottle(ctx, key.kind, key.value)
+		if err != nil {
+			return err
+		}
+		if throttle.RetryAfter > 0 && (blocked == nil || throttle.RetryAfter > blocked.RetryAfter) {
+			blocked = throttle
+		}
+	}
+	if blocked == nil {
+		return nil
+	}
+	err := &LoginThrottledError{Err: ErrLoginThrottled, RetryAfter: blocked.RetryAfter}
+	if blocked.Locked {
+		err.Err = ErrLoginLocked
+	}
+	return err
+}
+
+// loginFailed counts a failed login against the username and the IP address,
+// blocking them as their LoginPolicy says, and returns ErrWrongPassword.
+func (s *userService) loginFailed(ctx context.Context, username, clientIP string) error {
+	err := s.transactor.StartTx(ctx, func(tx *sql.Tx) error {
+		repository := s.repository.WithTx(tx)
+		for _, key := range loginKeys(username, clientIP) {
+			throttle, err := repository.RecordLoginFailure(ctx, key.kind, key.value, LoginFailureWindow)
+			if err != nil {
+				return err
+			}
+			block, locked := LoginPolicies[key.kind].backoff(throttle.Failures)
+			if block == 0 {
+				continue
+			}
+			err = repository.BlockLogin(ctx, key.kind, key.value, block, locked)
+			if err != nil {
+				return err
+			}
+			if !locked {
+				continue
+			}
+			err = repository.CreateLoginAudit(ctx, &LoginAudits{
+				Event:    LoginLocked,
+				Username: sql.NullString{String: username, Valid: true},
+				IP:       sql.NullString{String: clientIP, Valid: clientIP != ""},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return ErrWrongPassword
+}
+
+// issueTokens signs an access token and stores a new refresh token, both in the
+// session familyID. The access token carries the permissions of the user's role
+// and the ones granted to them.
+func (s *userService) issueTokens(ctx context.Context, repository Repository, userID uint64, userUID string, userType UserType, familyID string) (*UserAuthResponse, error) {
+	granted, err := repository.ListPermissions(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
+	permissions := uniquePermissions(append(slices.Clone(RolePermissions[userType]), granted...))
 
+	refreshToken := id.GenerateStringID(RefreshTokenLength)
+	err = repository.CreateRefreshToken(ctx, &RefreshTokens{
+		TokenHash: hashRefreshToken(refreshToken),
+		FamilyID:  familyID,
+		UserID:    userID,
+	}, RefreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	// create access token with signed jwt
+	accessToken, err := jwt.Sign(AccessTokenTTL, userUID, string(userType), familyID, permissions)
+	if err != nil {
+		return nil, err
+	}
 	return &UserAuthResponse{
-		AccessToken: accessToken,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
 	}, nil
 }
+
+func uniquePermissions(permissions []string) []string {
+	unique := append([]string{}, permissions...)
+	slices.Sort(unique)
+	return slices.Compact(unique)
+}
+
+// hashRefreshToken returns the digest refresh tokens are stored as. The tokens
+// are random enough that a fast unsalted hash suffices.
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,6 +1,11 @@
 package user
 
-import "time"
+import (
+	"database/sql"
+	"time"
+
+	"github.com/citadel-corp/belimang/internal/common/permission"
+)
 
 var (
 	MinUsername = 5
@@ -8,16 +13,75 @@ var (
 
 	MinPassword = 5
 	MaxPassword = 30
+
+	AccessTokenTTL     = 2 * time.Hour
+	RefreshTokenTTL    = 30 * 24 * time.Hour
+	RefreshTokenLength = 43 // about 256 random bits
+
+	LoginFailureWindow = 15 * time.Minute // failures are forgotten after that long without one
+	LoginBackoffBase   = time.Second
+	LoginBackoffMax    = 5 * time.Minute
+	LoginLockout       = 15 * time.Minute
+)
+
+type LoginThrottleKind string
+
+const (
+	UsernameThrottle LoginThrottleKind = "username"
+	IPThrottle       LoginThrottleKind = "ip"
 )
 
+// LoginPolicy is how many failed logins a username or an IP address is allowed.
+// Past FreeAttempts failures, every one blocks logins for twice as long as the
+// previous one, from LoginBackoffBase up to LoginBackoffMax. At LockAfter
+// failures, logins are locked out for LoginLockout.
+type LoginPolicy struct {
+	FreeAttempts int
+	LockAfter    int // zero never locks out
+}
+
+var LoginPolicies = map[LoginThrottleKind]LoginPolicy{
+	UsernameThrottle: {FreeAttempts: 3, LockAfter: 10},
+	// everyone behind a NAT shares an address, so it only backs off
+	IPThrottle: {FreeAttempts: 20},
+}
+
 type UserType string
 
 const (
-	Admin UserType = "Admin"
-	User  UserType = "User"
+	Admin         UserType = "Admin"
+	User          UserType = "User"
+	MerchantOwner UserType = "MerchantOwner" // manages the merchants granted to them
+	Courier       UserType = "Courier"
+	Support       UserType = "Support" // reads everything, changes nothing
 )
 
-var UserTypes = []interface{}{Admin, User}
+var UserTypes = []interface{}{Admin, User, MerchantOwner, Courier, Support}
+
+// StaffUserTypes are the roles admins create accounts for.
+var StaffUserTypes = []interface{}{MerchantOwner, Courier, Support}
+
+// RolePermissions are the permissions every user of a role has. Users are also
+// granted their own, see Repository.ListPermissions.
+var RolePermissions = map[UserType][]string{
+	Admin: {
+		permission.New(permission.Merchant, permission.Write, permission.Any),
+		permission.New(permission.Orders, permission.Write, permission.Any),
+		permission.New(permission.Orders, permission.Status, permission.Any),
+		permission.New(permission.Promotions, permission.Write, permission.Any),
+		permission.New(permission.Users, permission.Write, permission.Any),
+	},
+	Courier: {
+		permission.New(permission.Orders, permission.Read, permission.Any),
+		permission.New(permission.Orders, permission.Status, permission.Any),
+	},
+	Support: {
+		permission.New(permission.Merchant, permission.Read, permission.Any),
+		permission.New(permission.Orders, permission.Read, permission.Any),
+		permission.New(permission.Promotions, permission.Read, permission.Any),
+		permission.New(permission.Users, permission.Read, permission.Any),
+	},
+}
 
 type Users struct {
 	ID             uint64
@@ -28,3 +92,58 @@ type Users struct {
 	UserType       UserType
 	CreatedAt      time.Time
 }
+
+type RefreshTokens struct {
+	ID        uint64
+	TokenHash string
+	FamilyID  string
+	UserID    uint64
+	UserUID   string
+	UserType  UserType
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	RevokedAt sql.NullTime
+	CreatedAt time.Time
+}
+
+// LoginThrottles are the recent failed logins of a username or an IP address.
+type LoginThrottles struct {
+	Kind         LoginThrottleKind
+	Value        string
+	Failures     int
+	LastFailedAt time.Time
+	RetryAfter   time.Duration // until logins are let through again, zero when they are
+	Locked       bool          // blocked by a lockout rather than a backoff
+}
+
+type LoginAuditEvent string
+
+const (
+	LoginLocked   LoginAuditEvent = "locked"
+	LoginUnlocked LoginAuditEvent = "unlocked"
+)
+
+type LoginAudits struct {
+	ID        uint64
+	Event     LoginAuditEvent
+	Username  sql.NullString
+	IP        sql.NullString
+	ActorUID  sql.NullString // the admin who unlocked
+	CreatedAt time.Time
+}
+
+// backoff returns how long logins are blocked after failures failures, zero when
+// they are not, and whether it is a lockout.
+func (p LoginPolicy) backoff(failures int) (time.Duration, bool) {
+	if p.LockAfter > 0 && failures >= p.LockAfter {
+		return LoginLockout, true
+	}
+	if failures <= p.FreeAttempts {
+		return 0, false
+	}
+	d := LoginBackoffBase
+	for i := p.FreeAttempts + 1; i < failures && d < LoginBackoffMax; i++ {
+		d *= 2
+	}
+	return min(d, LoginBackoffMax), false
+}
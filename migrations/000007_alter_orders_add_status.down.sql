@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS order_status_histories;
+DROP INDEX IF EXISTS order_status_histories_order_id;
+DROP INDEX IF EXISTS order_status_histories_created_at_asc;
+
+DROP INDEX IF EXISTS orders_status;
+ALTER TABLE orders DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE orders DROP COLUMN IF EXISTS status;
+
+DROP TYPE IF EXISTS order_status;
@@ -0,0 +1,37 @@
+DROP TYPE IF EXISTS order_status;
+CREATE TYPE order_status AS ENUM(
+    'Placed',
+    'Accepted',
+    'Preparing',
+    'PickedUp',
+    'Delivered',
+    'Cancelled',
+    'Rejected'
+);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS status order_status NOT NULL DEFAULT 'Placed';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT current_timestamp;
+
+CREATE INDEX IF NOT EXISTS orders_status
+	ON orders USING HASH(status);
+
+CREATE TABLE IF NOT EXISTS
+order_status_histories (
+    id SERIAL PRIMARY KEY,
+    order_id CHAR(16) NOT NULL,
+    from_status order_status,
+    to_status order_status NOT NULL,
+    changed_by CHAR(16) NOT NULL,
+    created_at TIMESTAMP DEFAULT current_timestamp
+);
+
+ALTER TABLE order_status_histories ADD CONSTRAINT fk_order_status_histories_order_id
+    FOREIGN KEY (order_id)
+    REFERENCES orders(id)
+    ON DELETE CASCADE
+    ON UPDATE NO ACTION;
+
+CREATE INDEX IF NOT EXISTS order_status_histories_order_id
+	ON order_status_histories USING HASH(order_id);
+CREATE INDEX IF NOT EXISTS order_status_histories_created_at_asc
+	ON order_status_histories(created_at ASC);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS calculated_estimates_user_id;
+ALTER TABLE calculated_estimates DROP COLUMN IF EXISTS expires_at;
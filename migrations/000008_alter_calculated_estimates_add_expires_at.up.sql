@@ -0,0 +1,4 @@
+ALTER TABLE calculated_estimates ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP NOT NULL DEFAULT (current_timestamp + INTERVAL '30 minutes');
+
+CREATE INDEX IF NOT EXISTS calculated_estimates_user_id
+	ON calculated_estimates USING HASH(user_id);
@@ -0,0 +1 @@
+ALTER TABLE calculated_estimates DROP COLUMN IF EXISTS route;
@@ -0,0 +1 @@
+ALTER TABLE calculated_estimates ADD COLUMN IF NOT EXISTS route JSONB; -- object of ordered stops and the destination
@@ -0,0 +1 @@
+ALTER TABLE calculated_estimates ADD COLUMN IF NOT EXISTS price_breakdown JSONB; -- object of subtotal, fees, tax and total
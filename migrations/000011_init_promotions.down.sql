@@ -0,0 +1,11 @@
+ALTER TABLE calculated_estimates DROP COLUMN IF EXISTS promotion_id;
+
+DROP TABLE IF EXISTS promotion_usages;
+DROP INDEX IF EXISTS promotion_usages_promotion_id_user_id;
+
+DROP TABLE IF EXISTS promotions;
+DROP INDEX IF EXISTS promotions_code;
+DROP INDEX IF EXISTS promotions_uid;
+DROP INDEX IF EXISTS promotions_created_at_desc;
+
+DROP TYPE IF EXISTS discount_type;
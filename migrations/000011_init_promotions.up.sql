@@ -0,0 +1,56 @@
+DROP TYPE IF EXISTS discount_type;
+CREATE TYPE discount_type AS ENUM('Percentage', 'Flat');
+
+CREATE TABLE IF NOT EXISTS
+promotions (
+    id SERIAL PRIMARY KEY,
+    uid CHAR(16) UNIQUE NOT NULL,
+    code VARCHAR(30) NOT NULL,
+    discount_type discount_type NOT NULL,
+    discount_value INT NOT NULL, -- percentage for Percentage, amount for Flat
+    max_discount INT,
+    merchant_uids JSONB NOT NULL DEFAULT '[]', -- array of merchant uid, empty means every merchant
+    item_categories JSONB NOT NULL DEFAULT '[]', -- array of item category, empty means every category
+    min_spend INT NOT NULL DEFAULT 0,
+    usage_limit INT,
+    usage_limit_per_user INT,
+    usage_count INT NOT NULL DEFAULT 0,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT current_timestamp,
+    deleted_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS promotions_code
+	ON promotions (code) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS promotions_uid
+	ON promotions USING HASH(uid);
+CREATE INDEX IF NOT EXISTS promotions_created_at_desc
+	ON promotions(created_at DESC);
+
+CREATE TABLE IF NOT EXISTS
+promotion_usages (
+    id SERIAL PRIMARY KEY,
+    promotion_id BIGINT NOT NULL,
+    user_id CHAR(16) NOT NULL,
+    order_id CHAR(16) NOT NULL,
+    discount INT NOT NULL,
+    created_at TIMESTAMP DEFAULT current_timestamp
+);
+
+ALTER TABLE promotion_usages ADD CONSTRAINT fk_promotion_usages_promotion_id
+    FOREIGN KEY (promotion_id)
+    REFERENCES promotions(id)
+    ON DELETE CASCADE
+    ON UPDATE NO ACTION;
+
+ALTER TABLE promotion_usages ADD CONSTRAINT fk_promotion_usages_order_id
+    FOREIGN KEY (order_id)
+    REFERENCES orders(id)
+    ON DELETE CASCADE
+    ON UPDATE NO ACTION;
+
+CREATE INDEX IF NOT EXISTS promotion_usages_promotion_id_user_id
+	ON promotion_usages (promotion_id, user_id);
+
+ALTER TABLE calculated_estimates ADD COLUMN IF NOT EXISTS promotion_id CHAR(16);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS merchants_active_created_at_desc;
+
+ALTER TABLE merchants DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,4 @@
+ALTER TABLE merchants ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS merchants_active_created_at_desc
+	ON merchants(created_at DESC) WHERE deleted_at IS NULL;
@@ -0,0 +1 @@
+ALTER TABLE merchant_items DROP COLUMN IF EXISTS is_available;
@@ -0,0 +1 @@
+ALTER TABLE merchant_items ADD COLUMN IF NOT EXISTS is_available BOOLEAN NOT NULL DEFAULT TRUE;
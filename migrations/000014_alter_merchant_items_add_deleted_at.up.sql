@@ -0,0 +1 @@
+ALTER TABLE merchant_items ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
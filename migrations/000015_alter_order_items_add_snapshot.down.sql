@@ -0,0 +1,10 @@
+UPDATE order_items oi
+SET items = (
+    SELECT COALESCE(jsonb_agg(
+        e.item - 'name' - 'productCategory' - 'imageUrl' - 'lineTotal'
+        ORDER BY e.ord
+    ), '[]'::jsonb)
+    FROM jsonb_array_elements(oi.items) WITH ORDINALITY AS e(item, ord)
+);
+
+ALTER TABLE order_items DROP COLUMN IF EXISTS merchant;
@@ -0,0 +1,34 @@
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS merchant JSONB; -- object of merchant details at order time
+
+-- orders placed before snapshots were stored are backfilled from the current items and merchants
+UPDATE order_items oi
+SET items = (
+    SELECT COALESCE(jsonb_agg(
+        e.item || COALESCE((
+            SELECT jsonb_build_object(
+                'name', mi.name,
+                'productCategory', mi.item_category,
+                'price', COALESCE((e.item->>'price')::int, mi.price),
+                'imageUrl', mi.image_url,
+                'lineTotal', COALESCE((e.item->>'price')::int, mi.price) * (e.item->>'quantity')::int
+            )
+            FROM merchant_items mi
+            WHERE mi.uid = e.item->>'itemId'
+        ), '{}'::jsonb)
+        ORDER BY e.ord
+    ), '[]'::jsonb)
+    FROM jsonb_array_elements(oi.items) WITH ORDINALITY AS e(item, ord)
+);
+
+UPDATE order_items oi
+SET merchant = jsonb_build_object(
+    'merchantId', m.uid,
+    'name', m.name,
+    'merchantCategory', m.merchant_category,
+    'imageUrl', m.image_url,
+    'lat', m.location_lat,
+    'long', m.location_lng,
+    'createdAt', m.created_at
+)
+FROM merchants m
+WHERE m.uid = oi.merchant_id AND oi.merchant IS NULL;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS merchant_closures;
+DROP TABLE IF EXISTS merchant_opening_hours;
+
+ALTER TABLE merchants DROP COLUMN IF EXISTS timezone;
@@ -0,0 +1,38 @@
+ALTER TABLE merchants ADD COLUMN IF NOT EXISTS timezone VARCHAR NOT NULL DEFAULT 'UTC';
+
+CREATE TABLE IF NOT EXISTS
+merchant_opening_hours (
+    id SERIAL PRIMARY KEY,
+    merchant_id BIGINT NOT NULL,
+    day_of_week SMALLINT NOT NULL, -- 0 is Sunday
+    opens_minute SMALLINT NOT NULL, -- minutes since local midnight
+    closes_minute SMALLINT NOT NULL, -- at or before opens_minute when closing the next day
+    created_at TIMESTAMP DEFAULT current_timestamp
+);
+
+ALTER TABLE merchant_opening_hours ADD CONSTRAINT fk_merchant_opening_hours_merchant_id
+    FOREIGN KEY (merchant_id)
+    REFERENCES merchants(id)
+    ON DELETE CASCADE
+    ON UPDATE NO ACTION;
+
+CREATE INDEX IF NOT EXISTS merchant_opening_hours_merchant_id
+	ON merchant_opening_hours (merchant_id, day_of_week);
+
+CREATE TABLE IF NOT EXISTS
+merchant_closures (
+    id SERIAL PRIMARY KEY,
+    merchant_id BIGINT NOT NULL,
+    date DATE NOT NULL, -- local date of the merchant
+    reason VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT current_timestamp
+);
+
+ALTER TABLE merchant_closures ADD CONSTRAINT fk_merchant_closures_merchant_id
+    FOREIGN KEY (merchant_id)
+    REFERENCES merchants(id)
+    ON DELETE CASCADE
+    ON UPDATE NO ACTION;
+
+CREATE UNIQUE INDEX IF NOT EXISTS merchant_closures_merchant_id_date
+	ON merchant_closures (merchant_id, date);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS item_options;
+DROP TABLE IF EXISTS item_option_groups;
@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS
+item_option_groups (
+    id SERIAL PRIMARY KEY,
+    uid CHAR(16) UNIQUE NOT NULL,
+    item_id BIGINT NOT NULL,
+    name VARCHAR(30) NOT NULL,
+    min_selections INT NOT NULL DEFAULT 0,
+    max_selections INT NOT NULL,
+    created_at TIMESTAMP DEFAULT current_timestamp
+);
+
+ALTER TABLE item_option_groups ADD CONSTRAINT fk_item_option_groups_item_id
+    FOREIGN KEY (item_id)
+    REFERENCES merchant_items(id)
+    ON DELETE CASCADE
+    ON UPDATE NO ACTION;
+
+CREATE INDEX IF NOT EXISTS item_option_groups_item_id
+	ON item_option_groups USING HASH(item_id);
+
+CREATE TABLE IF NOT EXISTS
+item_options (
+    id SERIAL PRIMARY KEY,
+    uid CHAR(16) UNIQUE NOT NULL,
+    option_group_id BIGINT NOT NULL,
+    name VARCHAR(30) NOT NULL,
+    price_delta INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT current_timestamp
+);
+
+ALTER TABLE item_options ADD CONSTRAINT fk_item_options_option_group_id
+    FOREIGN KEY (option_group_id)
+    REFERENCES item_option_groups(id)
+    ON DELETE CASCADE
+    ON UPDATE NO ACTION;
+
+CREATE INDEX IF NOT EXISTS item_options_option_group_id
+	ON item_options USING HASH(option_group_id);
@@ -0,0 +1,14 @@
+CREATE INDEX IF NOT EXISTS merchants_name
+	ON merchants USING HASH (name);
+CREATE INDEX IF NOT EXISTS merchants_name_lower
+	ON merchants USING HASH (LOWER(name));
+CREATE INDEX IF NOT EXISTS merchant_items_name
+	ON merchant_items USING HASH(lower(name));
+
+DROP INDEX IF EXISTS merchants_search_vector;
+DROP INDEX IF EXISTS merchants_name_trgm;
+DROP INDEX IF EXISTS merchant_items_search_vector;
+DROP INDEX IF EXISTS merchant_items_name_trgm;
+
+ALTER TABLE merchants DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE merchant_items DROP COLUMN IF EXISTS search_vector;
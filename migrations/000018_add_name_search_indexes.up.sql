@@ -0,0 +1,23 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- the 'simple' configuration keeps names unstemmed, which suits merchant and menu names
+ALTER TABLE merchants
+    ADD COLUMN IF NOT EXISTS search_vector TSVECTOR
+    GENERATED ALWAYS AS (to_tsvector('simple', name)) STORED;
+ALTER TABLE merchant_items
+    ADD COLUMN IF NOT EXISTS search_vector TSVECTOR
+    GENERATED ALWAYS AS (to_tsvector('simple', name)) STORED;
+
+CREATE INDEX IF NOT EXISTS merchants_search_vector
+	ON merchants USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS merchants_name_trgm
+	ON merchants USING GIN (LOWER(name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS merchant_items_search_vector
+	ON merchant_items USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS merchant_items_name_trgm
+	ON merchant_items USING GIN (LOWER(name) gin_trgm_ops);
+
+-- hash indexes only serve equality, which no name filter uses
+DROP INDEX IF EXISTS merchants_name;
+DROP INDEX IF EXISTS merchants_name_lower;
+DROP INDEX IF EXISTS merchant_items_name;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS merchants_created_at_uid;
+DROP INDEX IF EXISTS merchant_items_merchant_id_created_at_uid;
+DROP INDEX IF EXISTS orders_user_id_created_at;
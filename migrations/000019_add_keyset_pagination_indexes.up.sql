@@ -0,0 +1,7 @@
+-- keyset pages seek on the sort key with its tie breaker
+CREATE INDEX IF NOT EXISTS merchants_created_at_uid
+	ON merchants (created_at, uid) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS merchant_items_merchant_id_created_at_uid
+	ON merchant_items (merchant_id, created_at, uid) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS orders_user_id_created_at
+	ON orders (user_id, created_at DESC);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS merchants_location_earth;
@@ -0,0 +1,4 @@
+-- serves both the earth_box prefilter and the <-> nearest neighbour ordering of nearby searches
+CREATE INDEX IF NOT EXISTS merchants_location_earth
+	ON merchants USING GIST (ll_to_earth(location_lat, location_lng))
+	WHERE deleted_at IS NULL;
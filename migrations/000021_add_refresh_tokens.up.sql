@@ -0,0 +1,29 @@
+-- refresh tokens are stored as their SHA-256 hex digest, never in plain text;
+-- the tokens rotated from the same login share a family
+CREATE TABLE IF NOT EXISTS
+refresh_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    token_hash CHAR(64) UNIQUE NOT NULL,
+    family_id CHAR(16) NOT NULL,
+    user_id BIGINT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT current_timestamp
+);
+
+ALTER TABLE refresh_tokens ADD CONSTRAINT fk_refresh_tokens_user_id
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+    ON UPDATE NO ACTION;
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_id
+	ON refresh_tokens (family_id);
+
+-- access tokens of a revoked session are rejected until they would have expired
+CREATE TABLE IF NOT EXISTS
+revoked_sessions (
+    session_id CHAR(16) PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL
+);
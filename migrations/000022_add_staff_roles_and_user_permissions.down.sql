@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_permissions;
+-- enum values cannot be dropped, so the staff roles stay in user_type
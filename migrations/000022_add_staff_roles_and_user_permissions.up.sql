@@ -0,0 +1,19 @@
+ALTER TYPE user_type ADD VALUE IF NOT EXISTS 'MerchantOwner';
+ALTER TYPE user_type ADD VALUE IF NOT EXISTS 'Courier';
+ALTER TYPE user_type ADD VALUE IF NOT EXISTS 'Support';
+
+-- permissions granted to a user on top of the ones of their role, e.g. a
+-- merchant owner's merchant:write:{merchantId}
+CREATE TABLE IF NOT EXISTS
+user_permissions (
+    user_id BIGINT NOT NULL,
+    permission VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT current_timestamp,
+    PRIMARY KEY (user_id, permission)
+);
+
+ALTER TABLE user_permissions ADD CONSTRAINT fk_user_permissions_user_id
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+    ON UPDATE NO ACTION;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS merchant_members_user_id;
+DROP TABLE IF EXISTS merchant_members;
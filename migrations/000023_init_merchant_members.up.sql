@@ -0,0 +1,23 @@
+-- staff linked to a merchant manage its menu, hours and incoming orders
+CREATE TABLE IF NOT EXISTS
+merchant_members (
+    merchant_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT current_timestamp,
+    PRIMARY KEY (merchant_id, user_id)
+);
+
+ALTER TABLE merchant_members ADD CONSTRAINT fk_merchant_members_merchant_id
+    FOREIGN KEY (merchant_id)
+    REFERENCES merchants(id)
+    ON DELETE CASCADE
+    ON UPDATE NO ACTION;
+
+ALTER TABLE merchant_members ADD CONSTRAINT fk_merchant_members_user_id
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+    ON UPDATE NO ACTION;
+
+CREATE INDEX IF NOT EXISTS merchant_members_user_id
+	ON merchant_members (user_id);
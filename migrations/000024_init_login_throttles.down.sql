@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS login_audits;
+DROP TABLE IF EXISTS login_throttles;
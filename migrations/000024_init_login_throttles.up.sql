@@ -0,0 +1,27 @@
+-- recent failed logins of a username or an IP address, which back off and, for
+-- usernames, lock out after too many of them; usernames are tracked whether or
+-- not a user has them, so lockouts tell nothing about which ones exist
+CREATE TABLE IF NOT EXISTS
+login_throttles (
+    kind VARCHAR(8) NOT NULL,
+    value VARCHAR(255) NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    blocked_until TIMESTAMP,
+    locked BOOLEAN NOT NULL DEFAULT false,
+    PRIMARY KEY (kind, value)
+);
+
+-- lockouts and the unlocks of admins
+CREATE TABLE IF NOT EXISTS
+login_audits (
+    id BIGSERIAL PRIMARY KEY,
+    event VARCHAR(16) NOT NULL,
+    username VARCHAR(255),
+    ip VARCHAR(45),
+    actor_uid CHAR(16),
+    created_at TIMESTAMP DEFAULT current_timestamp
+);
+
+CREATE INDEX IF NOT EXISTS login_audits_username
+	ON login_audits (username);
@@ -0,0 +1,12 @@
+-- token buckets of the rate limits shared by every instance, keyed by policy
+-- and client; full buckets are swept, being the same as none
+CREATE TABLE IF NOT EXISTS
+rate_limit_buckets (
+    key VARCHAR(255) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    full_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS rate_limit_buckets_full_at
+	ON rate_limit_buckets (full_at);